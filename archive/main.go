@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+const (
+	_OutDir   = "data"
+	_BlockNum = "15209997"
+)
+
+var (
+	rpcFlags = rpc.RegisterFlags(flag.CommandLine, _BlockNum)
+	outDir   = flag.String("out", _OutDir, "directory to write the fixtures and manifest to")
+	force    = flag.Bool("force", false, "fetch blocks again even when they are archived")
+)
+
+// kinds of fixture files written for each block, in the data/ naming scheme.
+// The empty kind is the block with full transactions, block-N.json.
+var kinds = []string{"", "header", "transactions", "receipts", "header-rlp", "block-rlp", "raw-receipts"}
+
+// unclesKind is the fixture of the uncles of a block, only written for
+// blocks with uncles
+const unclesKind = "uncles"
+
+// headerOnlyFields are the fields of a block that are not part of the header
+var headerOnlyFields = []string{"hash", "size", "totalDifficulty", "transactions", "uncles", "withdrawals"}
+
+// txHash is the hash field of a json transaction
+type txHash struct {
+	Hash common.Hash `json:"hash"`
+}
+
+// fetchBlock fetch the raw and json data of a block and return the content of
+// each fixture file by kind
+func fetchBlock(ctx context.Context, client *rpc.Client, blockNum uint64) (map[string]json.RawMessage, error) {
+	data, err := client.FetchFullBlockData(ctx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	block := data.BlockJSON
+
+	var blockFields map[string]json.RawMessage
+	if err := json.Unmarshal(block, &blockFields); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByNumber: %w", err)
+	}
+	var dec struct {
+		Hash         common.Hash   `json:"hash"`
+		Transactions []txHash      `json:"transactions"`
+		Uncles       []common.Hash `json:"uncles"`
+	}
+	if err := json.Unmarshal(block, &dec); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByNumber: %w", err)
+	}
+
+	// the raw header must be the one of the json block
+	if hash := crypto.Keccak256Hash(data.HeaderRlp); hash != dec.Hash {
+		return nil, fmt.Errorf("raw header hashes to %s, block json has hash %s", hash, dec.Hash)
+	}
+
+	receipts, err := fetchReceipts(ctx, client, dec.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.RawReceipts) != len(dec.Transactions) {
+		return nil, fmt.Errorf("%d raw receipts for %d transactions", len(data.RawReceipts), len(dec.Transactions))
+	}
+	rawReceipts := make([]string, len(data.RawReceipts))
+	for i, receipt := range data.RawReceipts {
+		rawReceipts[i] = hexutil.Encode(receipt)
+	}
+
+	header := make(map[string]json.RawMessage, len(blockFields))
+	for name, value := range blockFields {
+		header[name] = value
+	}
+	for _, name := range headerOnlyFields {
+		delete(header, name)
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	rawJSON := func(v interface{}) json.RawMessage {
+		enc, _ := json.Marshal(v) // strings only
+		return enc
+	}
+
+	contents := map[string]json.RawMessage{
+		"":             block,
+		"header":       headerJSON,
+		"transactions": blockFields["transactions"],
+		"receipts":     receipts,
+		"header-rlp":   rawJSON(hexutil.Encode(data.HeaderRlp)),
+		"block-rlp":    rawJSON(hexutil.Encode(data.BlockRlp)),
+		"raw-receipts": rawJSON(rawReceipts),
+	}
+	if len(dec.Uncles) > 0 {
+		if contents[unclesKind], err = fetchUncles(ctx, client, blockNum, dec.Uncles); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// fetchUncles fetch the json uncles of a block by index in one batch
+func fetchUncles(ctx context.Context, client *rpc.Client, blockNum uint64, hashes []common.Hash) (json.RawMessage, error) {
+	uncles := make([]json.RawMessage, len(hashes))
+	batch := make([]rpc.BatchElem, len(hashes))
+	for i := range hashes {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getUncleByBlockNumberAndIndex",
+			Params: []interface{}{hexutil.EncodeUint64(blockNum), hexutil.EncodeUint64(uint64(i))},
+			Result: &uncles[i],
+		}
+	}
+	if err := client.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("uncle %d: %w", i, elem.Error)
+		}
+		var dec struct {
+			Hash common.Hash `json:"hash"`
+		}
+		if err := json.Unmarshal(uncles[i], &dec); err != nil || dec.Hash != hashes[i] {
+			return nil, fmt.Errorf("uncle %d: not the uncle %s of the block", i, hashes[i])
+		}
+	}
+	return json.Marshal(uncles)
+}
+
+// fetchReceipts fetch the json receipts of the transactions in one batch
+func fetchReceipts(ctx context.Context, client *rpc.Client, txs []txHash) (json.RawMessage, error) {
+	receipts := make([]json.RawMessage, len(txs))
+	batch := make([]rpc.BatchElem, len(txs))
+	for i, tx := range txs {
+		batch[i] = rpc.BatchElem{Method: "eth_getTransactionReceipt", Params: []interface{}{tx.Hash}, Result: &receipts[i]}
+	}
+	if len(batch) > 0 {
+		if err := client.BatchCall(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, elem.Error)
+		}
+		if string(receipts[i]) == "null" {
+			return nil, fmt.Errorf("transaction %d: receipt of %s not found", i, txs[i].Hash)
+		}
+	}
+	return json.Marshal(receipts)
+}
+
+// ArchiveRange fetch the blocks from to to into dir and record them in the
+// manifest, which is saved after each block. Blocks whose files are all in
+// the manifest and unchanged are skipped unless force is set. It returns
+// the number of blocks archived and skipped.
+func ArchiveRange(ctx context.Context, client *rpc.Client, manifest *Manifest, dir string, from, to uint64, force bool) (int, int, error) {
+	archived, skipped := 0, 0
+	for blockNum := from; blockNum <= to; blockNum++ {
+		files := make([]string, len(kinds))
+		for i, kind := range kinds {
+			files[i] = loader.FixturePath(dir, int(blockNum), kind)
+		}
+		if !force && manifest.Has(files...) {
+			fmt.Printf("block %d: already archived, skipped\n", blockNum)
+			skipped++
+			continue
+		}
+
+		contents, err := fetchBlock(ctx, client, blockNum)
+		if err != nil {
+			return archived, skipped, fmt.Errorf("block %d: %w", blockNum, err)
+		}
+		for i, kind := range kinds {
+			if err := manifest.WriteJSON(files[i], contents[kind]); err != nil {
+				return archived, skipped, err
+			}
+		}
+		if uncles, ok := contents[unclesKind]; ok {
+			files = append(files, loader.FixturePath(dir, int(blockNum), unclesKind))
+			if err := manifest.WriteJSON(files[len(files)-1], uncles); err != nil {
+				return archived, skipped, err
+			}
+		}
+		// save the manifest after every block, so an interrupted run resumes
+		if err := manifest.Save(); err != nil {
+			return archived, skipped, err
+		}
+		fmt.Printf("block %d: archived %d files\n", blockNum, len(files))
+		archived++
+	}
+	return archived, skipped, nil
+}
+
+func main() {
+	flag.Parse()
+
+	config, err := rpcFlags.Config()
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	from, to, err := rpcFlags.Range()
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	client := rpc.NewClient(config)
+	ctx, cancel := rpcFlags.Context()
+	defer cancel()
+
+	manifest, err := LoadManifest(*outDir)
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+
+	fmt.Printf("Archiving blocks %d to %d from %s into %s\n", from, to, client.Endpoint(), *outDir)
+	archived, skipped, err := ArchiveRange(ctx, client, manifest, *outDir, from, to, *force)
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	fmt.Printf("%d blocks archived, %d skipped\n", archived, skipped)
+}
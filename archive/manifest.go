@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// _ManifestFile is the name of the manifest in the output directory. It has
+// the format of sha256sum, so it can be checked with `sha256sum -c`.
+const _ManifestFile = "SHA256SUMS"
+
+// Manifest holds the sha256 checksums of the files in a directory
+type Manifest struct {
+	dir  string
+	sums map[string]string // file name to hex checksum
+}
+
+// LoadManifest read the manifest of dir, or start an empty one when there is
+// none yet
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{dir: dir, sums: make(map[string]string)}
+	data, err := ioutil.ReadFile(filepath.Join(dir, _ManifestFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || len(fields[0]) != 2*sha256.Size {
+			return nil, fmt.Errorf("%s: line %d: expected \"<sha256>  <file>\"", _ManifestFile, line)
+		}
+		m.sums[fields[1]] = fields[0]
+	}
+	return m, scanner.Err()
+}
+
+// Has reports whether all files are in the manifest and unchanged on disk
+func (m *Manifest) Has(paths ...string) bool {
+	for _, path := range paths {
+		sum, ok := m.sums[filepath.Base(path)]
+		if !ok {
+			return false
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil || checksum(data) != sum {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteJSON write the json indented like the other fixtures and record its
+// checksum. The file is written under a temporary name first, so a file in
+// the manifest is always complete.
+func (m *Manifest) WriteJSON(path string, value json.RawMessage) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, value, "", "    "); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	out.WriteByte('\n')
+	if err := writeFileAtomic(path, out.Bytes()); err != nil {
+		return err
+	}
+	m.sums[filepath.Base(path)] = checksum(out.Bytes())
+	return nil
+}
+
+// Save write the manifest with its files sorted by name
+func (m *Manifest) Save() error {
+	names := make([]string, 0, len(m.sums))
+	for name := range m.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&out, "%s  %s\n", m.sums[name], name)
+	}
+	return writeFileAtomic(filepath.Join(m.dir, _ManifestFile), out.Bytes())
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic write data to a temporary file and rename it over path
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+const (
+	hiveDir = "../raw-data/data/hive"
+	// archiveFrom and archiveTo are the hive blocks archived, block 6 has
+	// an uncle
+	archiveFrom = 4
+	archiveTo   = 7
+)
+
+// archive runs ArchiveRange over the test blocks with a manifest loaded
+// from dir, as a new run of the command does
+func archive(t *testing.T, client *rpc.Client, dir string) (int, int, error) {
+	t.Helper()
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ArchiveRange(context.Background(), client, manifest, dir, archiveFrom, archiveTo, false)
+}
+
+func TestArchiveResume(t *testing.T) {
+	chain, err := mockgeth.LoadChain(hiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := mockgeth.NewServer(chain...)
+	httpServer := server.Start()
+	defer httpServer.Close()
+	config := rpc.DefaultConfig()
+	config.Endpoint = httpServer.URL
+	config.Retries = 0
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	dir := t.TempDir()
+	const blocks = archiveTo - archiveFrom + 1
+	if archived, skipped, err := archive(t, client, dir); err != nil || archived != blocks || skipped != 0 {
+		t.Fatalf("first run: %d archived, %d skipped, error %v, want %d archived", archived, skipped, err, blocks)
+	}
+	first := readFixtures(t, dir)
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := loader.FixturePath(dir, 6, unclesKind); !manifest.Has(path) {
+		t.Fatalf("%s is not in the manifest", path)
+	}
+
+	// every call fails, so a block fetched again fails the run
+	server.SetFaults(mockgeth.Fault{Error: &rpc.Error{Code: -32000, Message: "fetched again"}})
+	if archived, skipped, err := archive(t, client, dir); err != nil || archived != 0 || skipped != blocks {
+		t.Fatalf("second run: %d archived, %d skipped, error %v, want %d skipped", archived, skipped, err, blocks)
+	}
+
+	// a block with a changed file, or a file a run did not get to write,
+	// is fetched again, and only that block
+	if err := os.WriteFile(loader.FixturePath(dir, 5, "receipts"), []byte("[]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(loader.FixturePath(dir, 7, "raw-receipts")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := archive(t, client, dir); err == nil {
+		t.Fatal("changed block: not fetched again")
+	}
+	server.SetFaults()
+	if archived, skipped, err := archive(t, client, dir); err != nil || archived != 2 || skipped != blocks-2 {
+		t.Fatalf("after changes: %d archived, %d skipped, error %v, want 2 archived", archived, skipped, err)
+	}
+	for path, content := range first {
+		archived, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(archived) != string(content) {
+			t.Fatalf("%s: differs from the first run", path)
+		}
+	}
+}
+
+// readFixtures reads the fixture files of the archived blocks by path
+func readFixtures(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	files := make(map[string][]byte)
+	for number := archiveFrom; number <= archiveTo; number++ {
+		for _, kind := range kinds {
+			path := loader.FixturePath(dir, number, kind)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[path] = content
+		}
+	}
+	return files
+}
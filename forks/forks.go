@@ -0,0 +1,63 @@
+// Package forks holds the fork parameters shared by the loaders and the
+// checks: the chain config of mainnet and of genesis files, and the error
+// for the data of forks after Shanghai, which the go-ethereum version of
+// this module does not handle.
+package forks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ErrUnsupported is returned for the data of a fork after Shanghai: the
+// header fields and transaction types the go-ethereum version of this module
+// does not decode, and the header rules it does not have
+var ErrUnsupported = errors.New("unsupported fork")
+
+// mainnetCancunTime and mainnetPragueTime are the timestamps of the forks of
+// mainnet after Shanghai, which go-ethereum v1.11.6 predates
+const (
+	mainnetCancunTime = 1710338135
+	mainnetPragueTime = 1746612311
+)
+
+func newUint64(v uint64) *uint64 {
+	return &v
+}
+
+// ChainConfig returns the chain config of mainnet, with the timestamps of
+// its forks after Shanghai, for "mainnet", or else the one of the
+// genesis.json file at genesis
+func ChainConfig(genesis string) (*params.ChainConfig, error) {
+	if genesis == "mainnet" {
+		config := *params.MainnetChainConfig
+		config.CancunTime = newUint64(mainnetCancunTime)
+		config.PragueTime = newUint64(mainnetPragueTime)
+		return &config, nil
+	}
+	return ChainConfigFromGenesis(genesis)
+}
+
+// ChainConfigFromGenesis load the chain config of a genesis.json file, as
+// given to geth init
+func ChainConfigFromGenesis(path string) (*params.ChainConfig, error) {
+	byteValue, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var genesis struct {
+		Config *params.ChainConfig `json:"config"`
+	}
+	if err := json.Unmarshal(byteValue, &genesis); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if genesis.Config == nil || genesis.Config.ChainID == nil {
+		return nil, fmt.Errorf("%s: genesis has no chain config", path)
+	}
+	return genesis.Config, nil
+}
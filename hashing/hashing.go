@@ -0,0 +1,136 @@
+// Package hashing derives the trie roots that a block header commits to,
+// such as the transactions, receipts and withdrawals roots.
+package hashing
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/KohdMonkey/validate-ethereum-data/simpletrie"
+)
+
+type TrieUpdater interface {
+	Update([]byte, []byte) error
+	Reset()
+}
+
+// TrieHasher is the tool used to calculate the hash of derivable list.
+type TrieHasher interface {
+	Reset()
+	Update([]byte, []byte) error
+	Hash() common.Hash
+}
+
+// DerivableList is the input to DeriveSha.
+// It is implemented by the 'Transactions', 'Receipts' and 'Withdrawals' types
+// of go-ethereum.
+type DerivableList interface {
+	Len() int
+	EncodeIndex(int, *bytes.Buffer)
+}
+
+// from go-ethereum/core/types/hashing.go
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// from go-ethereum/core/types/hashing.go
+func encodeForDerive(list DerivableList, i int, buf *bytes.Buffer) []byte {
+	buf.Reset()
+	list.EncodeIndex(i, buf)
+	// It's really unfortunate that we need to do perform this copy.
+	// StackTrie holds onto the values until Hash is called, so the values
+	// written to it must not alias.
+	return common.CopyBytes(buf.Bytes())
+}
+
+// InsertTrieIndexOrder inserting items into a trie by their index
+func InsertTrieIndexOrder(list DerivableList, hasher TrieUpdater) {
+	keybuf := new(bytes.Buffer)
+	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer encodeBufferPool.Put(valueBuf)
+
+	for i := 0; i < list.Len(); i++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(i))
+		value := encodeForDerive(list, i, valueBuf)
+
+		hasher.Update(keybuf.Bytes(), value)
+	}
+}
+
+// OldDeriveSha is the old implementation of DeriveSha.
+func OldDeriveSha(list DerivableList, hasher TrieHasher) common.Hash {
+	hasher.Reset()
+
+	InsertTrieIndexOrder(list, hasher)
+	return hasher.Hash()
+}
+
+// InsertTrieByteOrder inserts items into a trie by the byte order of its key
+func InsertTrieByteOrder(list DerivableList, hasher TrieUpdater) {
+	hasher.Reset()
+	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer encodeBufferPool.Put(valueBuf)
+
+	// StackTrie requires values to be inserted in increasing hash order, which is not the
+	// order that `list` provides hashes in. This insertion sequence ensures that the
+	// order is correct.
+	var indexBuf []byte
+	for i := 1; i < list.Len() && i <= 0x7f; i++ {
+		indexBuf = rlp.AppendUint64(indexBuf[:0], uint64(i))
+		value := encodeForDerive(list, i, valueBuf)
+		hasher.Update(indexBuf, value)
+	}
+	if list.Len() > 0 {
+		indexBuf = rlp.AppendUint64(indexBuf[:0], 0)
+		value := encodeForDerive(list, 0, valueBuf)
+		hasher.Update(indexBuf, value)
+	}
+	for i := 0x80; i < list.Len(); i++ {
+		indexBuf = rlp.AppendUint64(indexBuf[:0], uint64(i))
+		value := encodeForDerive(list, i, valueBuf)
+		hasher.Update(indexBuf, value)
+	}
+}
+
+// DeriveSha creates the tree hashes of transactions and receipts in a block header.
+func DeriveSha(list DerivableList, hasher TrieHasher) common.Hash {
+	InsertTrieByteOrder(list, hasher)
+	return hasher.Hash()
+}
+
+// NewTrie returns an empty Trie backed by an in-memory database, which can
+// hash a list and prove its keys
+func NewTrie() *trie.Trie {
+	return trie.NewEmpty(trie.NewDatabase(rawdb.NewMemoryDatabase()))
+}
+
+// StackTrieRoot calculates the root hash of list using go-ethereum's StackTrie
+func StackTrieRoot(list DerivableList) common.Hash {
+	return DeriveSha(list, trie.NewStackTrie(nil))
+}
+
+// SimpleTrieRoot calculates the root hash of list using simpletrie
+func SimpleTrieRoot(list DerivableList) common.Hash {
+	t := simpletrie.NewTrie()
+	InsertTrieByteOrder(list, t)
+	return common.BytesToHash(t.Hash())
+}
+
+// EncodedList implements DerivableList for items that are already encoded,
+// such as the raw transactions of a block body.
+type EncodedList [][]byte
+
+// Len returns the length of s.
+func (s EncodedList) Len() int { return len(s) }
+
+// EncodeIndex writes the encoding of the i'th item to w.
+func (s EncodedList) EncodeIndex(i int, w *bytes.Buffer) {
+	w.Write(s[i])
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/pow"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+const (
+	_DefaultHeaders = "../raw-data/data/hive/headers-0-77.json"
+	_DefaultGenesis = "../raw-data/data/hive/genesis.json"
+
+	// _DefaultTrusted is the hash of block 77 of the hive test chain, the
+	// last header of the default segment
+	_DefaultTrusted = "0x49b74bc0dea88f3125f95f1eb9c0503e90440f7f23b362c4f66269a14a2dcc3e"
+)
+
+var (
+	headersFile = flag.String("headers", _DefaultHeaders, "json array of headers, as json objects or hex strings of rlp-encoded headers")
+	trusted     = flag.String("trusted", _DefaultTrusted, "trusted hash of the last header of the segment")
+	genesis     = flag.String("genesis", _DefaultGenesis, "genesis.json with the chain config of the forks, or mainnet")
+	seal        = flag.Bool("seal", false, "verify the Ethash seal of proof-of-work headers, which the hive test chain does not have")
+	td          = flag.String("td", "", "total difficulty of the first header, known from the genesis by default")
+	cacheDir    = flag.String("ethashdir", pow.DefaultDir(), "directory of the Ethash verification caches, \"\" to keep them in memory")
+)
+
+// TotalDifficulties returns the total difficulty of each header of the
+// segment, from td, the total difficulty of the first header, or nil when
+// td is nil
+func TotalDifficulties(headers []*types.Header, td *big.Int) []*big.Int {
+	if td == nil {
+		return nil
+	}
+	tds := make([]*big.Int, len(headers))
+	tds[0] = td
+	for i := 1; i < len(headers); i++ {
+		tds[i] = new(big.Int).Add(tds[i-1], headers[i].Difficulty)
+	}
+	return tds
+}
+
+// parentTD returns the total difficulty of the parent of header i, or nil
+// when the total difficulties are not known
+func parentTD(headers []*types.Header, tds []*big.Int, i int) *big.Int {
+	if tds == nil {
+		return nil
+	}
+	return new(big.Int).Sub(tds[i], headers[i].Difficulty)
+}
+
+// PrintForks print the header at which each fork of the segment starts, and
+// the terminal proof-of-work block when the segment crosses the merge
+func PrintForks(schedule verify.Schedule, headers []*types.Header, tds []*big.Int) {
+	fork := ""
+	for i, header := range headers {
+		merged := schedule.IsMerged(header, parentTD(headers, tds, i))
+		if name := schedule.Fork(header, merged); name != fork {
+			fmt.Printf("header #%d: %s\n", header.Number, name)
+			fork = name
+		}
+		if tds != nil && schedule.IsTerminal(tds[i], parentTD(headers, tds, i)) {
+			fmt.Printf("header #%d: terminal proof-of-work block, total difficulty %d reaches %d\n",
+				header.Number, tds[i], schedule.Config.TerminalTotalDifficulty)
+		}
+	}
+}
+
+// CheckRules check each header of the segment after the first against the
+// consensus rules of its fork, and print the headers that break one
+func CheckRules(schedule verify.Schedule, headers []*types.Header, tds []*big.Int) bool {
+	passed := true
+	for i := 1; i < len(headers); i++ {
+		for _, check := range verify.CheckHeaderRules(schedule, headers[i], headers[i-1], parentTD(headers, tds, i)) {
+			if check.Err != nil {
+				fmt.Printf("header #%d: %s: FAIL %v\n", headers[i].Number, check.Name, check.Err)
+				passed = false
+			}
+		}
+	}
+	return passed
+}
+
+// CheckSeals verify the Ethash seal of each proof-of-work header of the
+// segment, the first one included, and print the headers that do not pass
+func CheckSeals(verifier *pow.Verifier, schedule verify.Schedule, headers []*types.Header, tds []*big.Int) (bool, int) {
+	passed, sealed := true, 0
+	for i := range headers {
+		header := headers[i]
+		if schedule.IsMerged(header, parentTD(headers, tds, i)) {
+			continue
+		}
+		sealed++
+		if err := verifier.VerifySeal(header); err != nil {
+			fmt.Printf("header #%d: seal: FAIL %v\n", header.Number, err)
+			passed = false
+		}
+	}
+	return passed, sealed
+}
+
+func main() {
+	flag.Parse()
+
+	headers, err := loader.HeadersFromJSON(*headersFile)
+	cmdutil.PanicError(err)
+	if len(headers) == 0 {
+		cmdutil.ExitError(*headersFile + ": no headers")
+	}
+	first, last := headers[0].Number, headers[len(headers)-1].Number
+	fmt.Printf("Verifying %d headers, #%d to #%d, from %s\n", len(headers), first, last, *headersFile)
+
+	if err := verify.CheckAnchoredChain(headers, common.HexToHash(*trusted)); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+
+	fmt.Println("header chain matches")
+	fmt.Println("Trusted hash: ", *trusted)
+	fmt.Println("Oldest linked header: ", headers[0].Hash().String())
+
+	schedule, err := verify.LoadSchedule(*genesis)
+	cmdutil.PanicError(err)
+	firstTD, ok := new(big.Int), true
+	if *td != "" {
+		firstTD, ok = firstTD.SetString(*td, 0)
+	} else if headers[0].Number.Sign() == 0 {
+		firstTD.Set(headers[0].Difficulty)
+	} else {
+		firstTD = nil
+	}
+	if !ok {
+		cmdutil.ExitError("-td: invalid total difficulty " + *td)
+	}
+	tds := TotalDifficulties(headers, firstTD)
+	fmt.Println("Verifying header rules with the chain config of", *genesis)
+	PrintForks(schedule, headers, tds)
+	if !CheckRules(schedule, headers, tds) {
+		cmdutil.ExitError("header rules do not match")
+	}
+	fmt.Println("header rules match for", len(headers)-1, "headers")
+
+	if *seal {
+		verifier := pow.NewVerifier(*cacheDir)
+		passed, sealed := CheckSeals(verifier, schedule, headers, tds)
+		verifier.Close()
+		if !passed {
+			cmdutil.ExitError("Ethash seals do not match")
+		}
+		fmt.Println("Ethash seals match for", sealed, "headers")
+	}
+}
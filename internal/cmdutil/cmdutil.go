@@ -0,0 +1,21 @@
+// Package cmdutil holds the error handling shared by the commands of the
+// repository, which stop at the first error instead of returning it.
+package cmdutil
+
+import (
+	"fmt"
+	"os"
+)
+
+// PanicError panics with err, if any
+func PanicError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ExitError prints err and exits with status 1
+func ExitError(err string) {
+	fmt.Println(err)
+	os.Exit(1)
+}
@@ -0,0 +1,70 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Block is a block saved from eth_getBlockByNumber with full transaction
+// objects
+type Block struct {
+	Header       *types.Header
+	Hash         common.Hash // hash reported by the client
+	Transactions []*types.Transaction
+	Uncles       []common.Hash
+
+	// Withdrawals and WithdrawalsRoot are only set for post-Shanghai blocks
+	Withdrawals     types.Withdrawals
+	WithdrawalsRoot *common.Hash
+}
+
+// blockJSON holds the fields of a block that are not part of types.Header
+type blockJSON struct {
+	Hash            *common.Hash      `json:"hash"`
+	Transactions    []json.RawMessage `json:"transactions"`
+	Uncles          []common.Hash     `json:"uncles"`
+	Withdrawals     types.Withdrawals `json:"withdrawals"`
+	WithdrawalsRoot *common.Hash      `json:"withdrawalsRoot"`
+}
+
+// BlockFromJSON load a block with full transaction objects from json file.
+// The header must hash to the hash of the json, and every transaction must
+// belong to the block.
+func BlockFromJSON(path string) (*Block, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dec blockJSON
+	if err := json.Unmarshal(byteValue, &dec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if dec.Hash == nil {
+		return nil, fmt.Errorf("%s: missing required field 'hash'", path)
+	}
+	if dec.Transactions == nil {
+		return nil, fmt.Errorf("%s: missing required field 'transactions'", path)
+	}
+	header, err := decodeHeader(byteValue)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	number := header.Number.Uint64()
+	txs, err := transactionsFromElements(path, dec.Transactions, BlockID{Hash: dec.Hash, Number: &number})
+	if err != nil {
+		return nil, err
+	}
+	return &Block{
+		Header:          header,
+		Hash:            *dec.Hash,
+		Transactions:    txs,
+		Uncles:          dec.Uncles,
+		Withdrawals:     dec.Withdrawals,
+		WithdrawalsRoot: dec.WithdrawalsRoot,
+	}, nil
+}
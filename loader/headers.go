@@ -0,0 +1,75 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+)
+
+// laterForkHeaderFields are the json header fields of the forks after
+// Shanghai. types.Header drops them when decoding, and would hash the header
+// without them.
+var laterForkHeaderFields = []string{"blobGasUsed", "excessBlobGas", "parentBeaconBlockRoot", "requestsHash"}
+
+// decodeHeader decode a json header. A field of a fork after Shanghai is an
+// forks.ErrUnsupported, and a header with a hash must hash to it.
+func decodeHeader(data []byte) (*types.Header, error) {
+	var header types.Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range laterForkHeaderFields {
+		if value, ok := fields[name]; ok && string(value) != "null" {
+			return nil, fmt.Errorf("%w: header field '%s' cannot be hashed", forks.ErrUnsupported, name)
+		}
+	}
+	if value, ok := fields["hash"]; ok && string(value) != "null" {
+		var hash common.Hash
+		if err := json.Unmarshal(value, &hash); err != nil {
+			return nil, fmt.Errorf("hash: %w", err)
+		}
+		if headerHash := header.Hash(); headerHash != hash {
+			return nil, fmt.Errorf("header hashes to %s, json hash is %s", headerHash, hash)
+		}
+	}
+	return &header, nil
+}
+
+// HeadersFromJSON load a segment of headers from a json array. Each element
+// is either a json header, as returned by eth_getBlockByNumber, or the hex
+// string of an rlp-encoded header, as returned by debug_getRawHeader. A json
+// header must hash to its hash, when it has one.
+func HeadersFromJSON(path string) ([]*types.Header, error) {
+	elements, err := readElements(path)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]*types.Header, len(elements))
+	for i, element := range elements {
+		var encoded string
+		if json.Unmarshal(element, &encoded) == nil {
+			headerBytes, err := hexutil.Decode(encoded)
+			if err != nil {
+				return nil, &ElementError{File: path, Index: i, Err: err}
+			}
+			if headers[i], err = BytesToHeader(headerBytes); err != nil {
+				return nil, &ElementError{File: path, Index: i, Err: err}
+			}
+			continue
+		}
+		if headers[i], err = decodeHeader(element); err != nil {
+			return nil, &ElementError{File: path, Index: i, Err: fmt.Errorf("expected a header or a hex string: %w", err)}
+		}
+	}
+	return headers, nil
+}
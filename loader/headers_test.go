@@ -0,0 +1,152 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+)
+
+// shanghaiBlock is a mainnet block with withdrawals, whose withdrawalsRoot
+// is part of the header hash
+const (
+	shanghaiBlock     = "../trie-test/data/block-18189758.json"
+	shanghaiBlockHash = "0x802acf5c350f4252e31d83c431fcb259470250fa0edf49e8391cfee014239820"
+)
+
+// writeHeader writes the header of the Shanghai block, with fields set to
+// the given json values, as a one element headers segment
+func writeHeader(t *testing.T, fields map[string]string) string {
+	t.Helper()
+	data, err := os.ReadFile(shanghaiBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatal(err)
+	}
+	delete(header, "transactions")
+	delete(header, "withdrawals")
+	for name, value := range fields {
+		header[name] = json.RawMessage(value)
+	}
+	data, err = json.Marshal([]interface{}{header})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "headers.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHeaderFromJSONShanghai(t *testing.T) {
+	header, err := loader.HeaderFromJSON(shanghaiBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash := header.Hash(); hash != common.HexToHash(shanghaiBlockHash) {
+		t.Fatalf("header hashes to %s, want %s", hash, shanghaiBlockHash)
+	}
+	if header.WithdrawalsHash == nil {
+		t.Fatal("withdrawalsRoot was dropped")
+	}
+}
+
+func TestHeadersFromJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		err    string // part of the error, no error when empty
+	}{
+		{name: "shanghai header"},
+		{
+			name:   "without its hash",
+			fields: map[string]string{"hash": "null"},
+		},
+		{
+			name:   "wrong hash",
+			fields: map[string]string{"extraData": `"0x00"`},
+			err:    "json hash is " + shanghaiBlockHash,
+		},
+		{
+			name:   "cancun field",
+			fields: map[string]string{"blobGasUsed": `"0x0"`, "excessBlobGas": `"0x0"`},
+			err:    "unsupported fork",
+		},
+		{
+			name:   "parent beacon block root",
+			fields: map[string]string{"parentBeaconBlockRoot": `"` + common.Hash{}.Hex() + `"`},
+			err:    "'parentBeaconBlockRoot' cannot be hashed",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			headers, err := loader.HeadersFromJSON(writeHeader(t, test.fields))
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Fatalf("expected an error with %q", test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Fatalf("error %q, want it to contain %q", err, test.err)
+			case test.err != "":
+				return
+			}
+			if len(headers) != 1 {
+				t.Fatalf("%d headers, want 1", len(headers))
+			}
+			if test.fields["hash"] == "" && headers[0].Hash() != common.HexToHash(shanghaiBlockHash) {
+				t.Fatalf("header hashes to %s, want %s", headers[0].Hash(), shanghaiBlockHash)
+			}
+		})
+	}
+	if _, err := loader.HeadersFromJSON(writeHeader(t, map[string]string{"blobGasUsed": `"0x0"`})); !errors.Is(err, forks.ErrUnsupported) {
+		t.Fatalf("expected %v, got %v", forks.ErrUnsupported, err)
+	}
+}
+
+func TestBytesToHeaderUnsupportedFork(t *testing.T) {
+	header, err := loader.HeaderFromJSON(shanghaiBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.BytesToHeader(encoded); err != nil {
+		t.Fatalf("shanghai header: %v", err)
+	}
+
+	// a Cancun header adds blobGasUsed, excessBlobGas and
+	// parentBeaconBlockRoot
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(encoded, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []interface{}{uint64(0), uint64(0), common.Hash{}} {
+		field, err := rlp.EncodeToBytes(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, field)
+	}
+	if encoded, err = rlp.EncodeToBytes(fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.BytesToHeader(encoded); !errors.Is(err, forks.ErrUnsupported) {
+		t.Fatalf("cancun header: error %v, want %v", err, forks.ErrUnsupported)
+	}
+}
@@ -0,0 +1,138 @@
+// Package loader reads blocks, headers, transactions and receipts that were
+// saved from JSON-RPC responses into go-ethereum types.
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FixturePath returns the path of the block-<num>-<kind>.json fixture in
+// dataDir, or of block-<num>.json for the full block when kind is empty
+func FixturePath(dataDir string, blockNum int, kind string) string {
+	name := "block-" + strconv.Itoa(blockNum)
+	if kind != "" {
+		name += "-" + kind
+	}
+	return dataDir + "/" + name + ".json"
+}
+
+// readFile reads the whole fixture file
+func readFile(path string) ([]byte, error) {
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer jsonFile.Close()
+
+	byteValue, err := ioutil.ReadAll(jsonFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return byteValue, nil
+}
+
+// TransactionsFromJSON load transactions from json file. Every element must
+// be a transaction of a known type with its block fields present.
+func TransactionsFromJSON(path string) ([]*types.Transaction, error) {
+	return BlockTransactionsFromJSON(path, BlockID{})
+}
+
+// ReceiptsFromJSON load receipts from json file. Every element must be a
+// receipt of a known type with its block fields present.
+func ReceiptsFromJSON(path string) ([]*types.Receipt, error) {
+	return BlockReceiptsFromJSON(path, BlockID{})
+}
+
+// HeaderFromJSON load header from json file. The header must hash to its
+// hash, when it has one.
+func HeaderFromJSON(path string) (*types.Header, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := decodeHeader(byteValue)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return header, nil
+}
+
+// UnclesFromJSON load the uncle headers of a block, saved as the list of
+// eth_getUncleByBlockNumberAndIndex results, and the hashes reported with
+// them
+func UnclesFromJSON(path string) ([]*types.Header, []common.Hash, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(byteValue, &elements); err != nil {
+		return nil, nil, fmt.Errorf("%s: expected a json array: %w", path, err)
+	}
+	headers := make([]*types.Header, len(elements))
+	hashes := make([]common.Hash, len(elements))
+	for i, element := range elements {
+		headers[i] = new(types.Header)
+		if err := json.Unmarshal(element, headers[i]); err != nil {
+			return nil, nil, &ElementError{File: path, Index: i, Err: err}
+		}
+		var dec struct {
+			Hash *common.Hash `json:"hash"`
+		}
+		if err := json.Unmarshal(element, &dec); err != nil {
+			return nil, nil, &ElementError{File: path, Index: i, Err: err}
+		}
+		if dec.Hash == nil {
+			return nil, nil, &ElementError{File: path, Index: i, Field: "hash", Err: fmt.Errorf("missing required field 'hash'")}
+		}
+		hashes[i] = *dec.Hash
+	}
+	return headers, hashes, nil
+}
+
+// BlockTotalDifficulty returns the totalDifficulty of a block returned by
+// eth_getBlockByNumber, or nil when the node does not report it
+func BlockTotalDifficulty(blockJSON []byte) (*big.Int, error) {
+	var dec struct {
+		TotalDifficulty *hexutil.Big `json:"totalDifficulty"`
+	}
+	if err := json.Unmarshal(blockJSON, &dec); err != nil {
+		return nil, err
+	}
+	if dec.TotalDifficulty == nil {
+		return nil, nil
+	}
+	return (*big.Int)(dec.TotalDifficulty), nil
+}
+
+// WithdrawalsFromJSON load the withdrawals and the header's withdrawals root
+// from a block returned by eth_getBlockByNumber
+func WithdrawalsFromJSON(path string) (types.Withdrawals, common.Hash, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	var block struct {
+		Withdrawals     types.Withdrawals `json:"withdrawals"`
+		WithdrawalsRoot *common.Hash      `json:"withdrawalsRoot"`
+	}
+	if err := json.Unmarshal(byteValue, &block); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if block.WithdrawalsRoot == nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: block has no withdrawals root", path)
+	}
+	return block.Withdrawals, *block.WithdrawalsRoot, nil
+}
@@ -0,0 +1,194 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+)
+
+// shanghaiHeaderFields is the number of fields of a Shanghai header. The
+// headers of later forks have more, which types.Header does not decode.
+const shanghaiHeaderFields = 17
+
+// BytesToHeader decode rlp-encoded header. A header of a fork after Shanghai
+// is a forks.ErrUnsupported.
+func BytesToHeader(dataBytes []byte) (*types.Header, error) {
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(dataBytes, &fields); err == nil && len(fields) > shanghaiHeaderFields {
+		return nil, fmt.Errorf("%w: header has %d fields, a Shanghai header has %d", forks.ErrUnsupported, len(fields), shanghaiHeaderFields)
+	}
+	var header *types.Header
+	if err := rlp.DecodeBytes(dataBytes, &header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// BytesToReceipts decode a list of binary-encoded receipts, as returned by
+// debug_getRawReceipts
+func BytesToReceipts(encoded [][]byte) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, len(encoded))
+	for i, receiptBytes := range encoded {
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalBinary(receiptBytes); errors.Is(err, types.ErrTxTypeNotSupported) {
+			return nil, fmt.Errorf("%w: receipt %d: %v", forks.ErrUnsupported, i, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("error unmarshalling receipt %d: %w", i, err)
+		}
+	}
+	return receipts, nil
+}
+
+// Positions of the header fields that commit to the block body
+const (
+	headerUncleHashField       = 1
+	headerTxHashField          = 4
+	headerWithdrawalsHashField = 16 // from Shanghai
+)
+
+// RawBlock is an rlp-encoded block split into its encoded parts. Unlike
+// types.Block it keeps header fields and transaction types the go-ethereum
+// version of this module does not know, so blocks of any fork can be checked.
+type RawBlock struct {
+	Header       rlp.RawValue   // rlp-encoded header, whose hash is the block hash
+	HeaderFields []rlp.RawValue // rlp-encoded header fields in order
+	// Transactions are the binary encodings of the transactions, as they are
+	// inserted in the transactions trie
+	Transactions [][]byte
+	Uncles       rlp.RawValue // rlp-encoded list of uncle headers
+	// Withdrawals are only set from Shanghai, HasWithdrawals tells an empty
+	// list apart from a block without one
+	Withdrawals    types.Withdrawals
+	HasWithdrawals bool
+}
+
+// SplitRawBlock split an rlp-encoded block into its header, transactions,
+// uncles and withdrawals
+func SplitRawBlock(dataBytes []byte) (*RawBlock, error) {
+	var parts []rlp.RawValue
+	if err := rlp.DecodeBytes(dataBytes, &parts); err != nil {
+		return nil, fmt.Errorf("block: %w", err)
+	}
+	if len(parts) < 3 || len(parts) > 4 {
+		return nil, fmt.Errorf("block has %d parts, expected header, transactions, uncles and optional withdrawals", len(parts))
+	}
+
+	block := &RawBlock{Header: parts[0], Uncles: parts[2]}
+	if err := rlp.DecodeBytes(parts[0], &block.HeaderFields); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	var uncles []rlp.RawValue
+	if err := rlp.DecodeBytes(parts[2], &uncles); err != nil {
+		return nil, fmt.Errorf("uncles: %w", err)
+	}
+
+	var txs []rlp.RawValue
+	if err := rlp.DecodeBytes(parts[1], &txs); err != nil {
+		return nil, fmt.Errorf("transactions: %w", err)
+	}
+	block.Transactions = make([][]byte, len(txs))
+	for i, tx := range txs {
+		kind, content, _, err := rlp.Split(tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		// legacy transactions are lists, typed transactions are strings
+		// holding the type byte and the payload
+		if kind == rlp.List {
+			block.Transactions[i] = tx
+		} else {
+			block.Transactions[i] = content
+		}
+	}
+
+	if len(parts) == 4 {
+		block.HasWithdrawals = true
+		if err := rlp.DecodeBytes(parts[3], &block.Withdrawals); err != nil {
+			return nil, fmt.Errorf("withdrawals: %w", err)
+		}
+	}
+	return block, nil
+}
+
+// Hash returns the block hash, the keccak256 hash of the rlp-encoded header
+func (b *RawBlock) Hash() common.Hash {
+	return crypto.Keccak256Hash(b.Header)
+}
+
+// headerHash decodes the hash at a position of the header
+func (b *RawBlock) headerHash(field int) (common.Hash, error) {
+	var hash common.Hash
+	if field >= len(b.HeaderFields) {
+		return hash, fmt.Errorf("header has no field %d", field)
+	}
+	if err := rlp.DecodeBytes(b.HeaderFields[field], &hash); err != nil {
+		return hash, fmt.Errorf("header field %d: %w", field, err)
+	}
+	return hash, nil
+}
+
+// UncleHash returns the sha3Uncles field of the header
+func (b *RawBlock) UncleHash() (common.Hash, error) {
+	return b.headerHash(headerUncleHashField)
+}
+
+// TxHash returns the transactionsRoot field of the header
+func (b *RawBlock) TxHash() (common.Hash, error) {
+	return b.headerHash(headerTxHashField)
+}
+
+// WithdrawalsHash returns the withdrawalsRoot field of the header, nil for
+// headers from before Shanghai
+func (b *RawBlock) WithdrawalsHash() (*common.Hash, error) {
+	if len(b.HeaderFields) <= headerWithdrawalsHashField {
+		return nil, nil
+	}
+	hash, err := b.headerHash(headerWithdrawalsHashField)
+	return &hash, err
+}
+
+// RawFromJSON load a raw fixture saved by archive, such as
+// block-N-block-rlp.json, which holds the hex string returned by the node
+func RawFromJSON(path string) ([]byte, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(byteValue, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// UncleHeaders decode the uncle headers of the block
+func (b *RawBlock) UncleHeaders() ([]*types.Header, error) {
+	var uncles []*types.Header
+	if err := rlp.DecodeBytes(b.Uncles, &uncles); err != nil {
+		return nil, fmt.Errorf("uncles: %w", err)
+	}
+	return uncles, nil
+}
+
+// DecodeTransactions decode the transactions of the block from their binary
+// encodings, without the header, which may have fields types.Header lacks
+func (b *RawBlock) DecodeTransactions() ([]*types.Transaction, error) {
+	txs := make([]*types.Transaction, len(b.Transactions))
+	for i, txBytes := range b.Transactions {
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalBinary(txBytes); errors.Is(err, types.ErrTxTypeNotSupported) {
+			return nil, fmt.Errorf("%w: transaction %d: %v", forks.ErrUnsupported, i, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return txs, nil
+}
@@ -0,0 +1,222 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockID identifies the block that every element of a fixture must belong
+// to. Fields left nil are not checked.
+type BlockID struct {
+	Hash   *common.Hash
+	Number *uint64
+}
+
+// NumberID returns a BlockID that only checks the block number
+func NumberID(blockNum uint64) BlockID {
+	return BlockID{Number: &blockNum}
+}
+
+// ElementError reports a problem with one element of a json fixture
+type ElementError struct {
+	File  string
+	Index int
+	Field string // json field at fault, empty when the element is
+	Err   error
+}
+
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("%s: element %d: %v", e.File, e.Index, e.Err)
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// fields required in every transaction from eth_getBlockByNumber or
+// eth_getTransactionByHash, on top of the ones go-ethereum checks per type
+var requiredTransactionFields = []string{
+	"type", "hash", "blockHash", "blockNumber", "transactionIndex",
+}
+
+// fields required in every receipt from eth_getTransactionReceipt
+var requiredReceiptFields = []string{
+	"type", "transactionHash", "transactionIndex", "blockHash", "blockNumber",
+	"cumulativeGasUsed", "gasUsed", "logs", "logsBloom",
+}
+
+// elementMeta holds the fields shared by transactions and receipts that
+// place them in a block
+type elementMeta struct {
+	Type        *hexutil.Uint64 `json:"type"`
+	BlockHash   *common.Hash    `json:"blockHash"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+}
+
+// readElements splits a json array fixture into its elements
+func readElements(path string) ([]json.RawMessage, error) {
+	byteValue, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(byteValue, &elements); err != nil {
+		return nil, fmt.Errorf("%s: expected a json array: %w", path, err)
+	}
+	return elements, nil
+}
+
+// checkElement checks that the required fields of an element are present,
+// that its type is known and that it belongs to the expected block. It
+// returns the raw fields of the element for further checks, or the field at
+// fault with the error.
+func checkElement(element json.RawMessage, required []string, block BlockID) (fields map[string]json.RawMessage, field string, err error) {
+	if err := json.Unmarshal(element, &fields); err != nil {
+		return nil, "", err
+	}
+	for _, name := range required {
+		if value, ok := fields[name]; !ok || string(value) == "null" {
+			return nil, name, fmt.Errorf("missing required field '%s'", name)
+		}
+	}
+
+	var meta elementMeta
+	if err := json.Unmarshal(element, &meta); err != nil {
+		return nil, "", err
+	}
+	switch uint8(*meta.Type) {
+	case types.LegacyTxType, types.AccessListTxType, types.DynamicFeeTxType:
+	default:
+		return nil, "type", fmt.Errorf("unknown transaction type %#x", uint64(*meta.Type))
+	}
+	if block.Hash != nil && *meta.BlockHash != *block.Hash {
+		return nil, "blockHash", fmt.Errorf("block hash %s, expected %s", meta.BlockHash, block.Hash)
+	}
+	if block.Number != nil && uint64(*meta.BlockNumber) != *block.Number {
+		return nil, "blockNumber", fmt.Errorf("block number %d, expected %d", uint64(*meta.BlockNumber), *block.Number)
+	}
+	return fields, "", nil
+}
+
+// BlockTransactionsFromJSON load transactions from json file, checking each
+// one against block
+func BlockTransactionsFromJSON(path string, block BlockID) ([]*types.Transaction, error) {
+	elements, err := readElements(path)
+	if err != nil {
+		return nil, err
+	}
+	return transactionsFromElements(path, elements, block)
+}
+
+// transactionsFromElements decode the transactions of a json array fixture
+func transactionsFromElements(path string, elements []json.RawMessage, block BlockID) ([]*types.Transaction, error) {
+	txs := make([]*types.Transaction, len(elements))
+	for i, element := range elements {
+		if _, field, err := checkElement(element, requiredTransactionFields, block); err != nil {
+			return nil, &ElementError{File: path, Index: i, Field: field, Err: err}
+		}
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalJSON(element); err != nil {
+			return nil, &ElementError{File: path, Index: i, Err: err}
+		}
+	}
+	return txs, nil
+}
+
+// BlockSendersFromJSON load the "from" address of each transaction of a
+// json fixture, checking each one against block
+func BlockSendersFromJSON(path string, block BlockID) ([]common.Address, error) {
+	elements, err := readElements(path)
+	if err != nil {
+		return nil, err
+	}
+
+	required := append([]string{"from"}, requiredTransactionFields...)
+	senders := make([]common.Address, len(elements))
+	for i, element := range elements {
+		if _, field, err := checkElement(element, required, block); err != nil {
+			return nil, &ElementError{File: path, Index: i, Field: field, Err: err}
+		}
+		var dec struct {
+			From common.Address `json:"from"`
+		}
+		if err := json.Unmarshal(element, &dec); err != nil {
+			return nil, &ElementError{File: path, Index: i, Err: err}
+		}
+		senders[i] = dec.From
+	}
+	return senders, nil
+}
+
+// TxRef is the hash and the position in its block that a json transaction
+// claims
+type TxRef struct {
+	Hash  common.Hash
+	Index uint64
+}
+
+// BlockTxRefsFromJSON load the hash and transactionIndex of each
+// transaction of a json fixture, checking each one against block
+func BlockTxRefsFromJSON(path string, block BlockID) ([]TxRef, error) {
+	elements, err := readElements(path)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]TxRef, len(elements))
+	for i, element := range elements {
+		if _, field, err := checkElement(element, requiredTransactionFields, block); err != nil {
+			return nil, &ElementError{File: path, Index: i, Field: field, Err: err}
+		}
+		var dec struct {
+			Hash             common.Hash    `json:"hash"`
+			TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+		}
+		if err := json.Unmarshal(element, &dec); err != nil {
+			return nil, &ElementError{File: path, Index: i, Err: err}
+		}
+		refs[i] = TxRef{Hash: dec.Hash, Index: uint64(dec.TransactionIndex)}
+	}
+	return refs, nil
+}
+
+// BlockReceiptsFromJSON load receipts from json file, checking each one
+// against block
+func BlockReceiptsFromJSON(path string, block BlockID) ([]*types.Receipt, error) {
+	elements, err := readElements(path)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]*types.Receipt, len(elements))
+	for i, element := range elements {
+		fields, field, err := checkElement(element, requiredReceiptFields, block)
+		if err != nil {
+			return nil, &ElementError{File: path, Index: i, Field: field, Err: err}
+		}
+		// receipts carry either the post-byzantium status or the state root
+		status, hasStatus := fields["status"]
+		root, hasRoot := fields["root"]
+		switch {
+		case !hasStatus && !hasRoot:
+			err := fmt.Errorf("missing required field 'status' or 'root'")
+			return nil, &ElementError{File: path, Index: i, Field: "status", Err: err}
+		case hasStatus && string(status) == "null":
+			err := fmt.Errorf("missing required field 'status'")
+			return nil, &ElementError{File: path, Index: i, Field: "status", Err: err}
+		case hasRoot && string(root) == "null":
+			err := fmt.Errorf("missing required field 'root'")
+			return nil, &ElementError{File: path, Index: i, Field: "root", Err: err}
+		}
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalJSON(element); err != nil {
+			return nil, &ElementError{File: path, Index: i, Err: err}
+		}
+	}
+	return receipts, nil
+}
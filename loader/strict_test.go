@@ -0,0 +1,144 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+)
+
+const (
+	blockNum         = 15209997
+	blockHash        = "0x868248867378bf14da3923ba2242e00a97154f390956ee5d5f7793f97920c047"
+	transactionsFile = "../raw-data/data/block-15209997-transactions.json"
+	receiptsFile     = "../raw-data/data/block-15209997-receipts.json"
+	brokenElement    = 1
+	removeField      = "" // value of a field to remove from the element
+	otherBlockHash   = `"0x0000000000000000000000000000000000000000000000000000000000000001"`
+	otherBlockNumber = `"0xe8190e"`
+)
+
+// writeElements writes the elements of a json array fixture, with fields of
+// the element at brokenElement set to the given json values or removed
+func writeElements(t *testing.T, fixture string, fields map[string]string) string {
+	t.Helper()
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var elements []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range fields {
+		if value == removeField {
+			delete(elements[brokenElement], name)
+		} else {
+			elements[brokenElement][name] = json.RawMessage(value)
+		}
+	}
+	if data, err = json.Marshal(elements); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), filepath.Base(fixture))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestElementErrors(t *testing.T) {
+	hash := common.HexToHash(blockHash)
+	byHash := loader.BlockID{Hash: &hash}
+	loadTransactions := func(path string, block loader.BlockID) error {
+		_, err := loader.BlockTransactionsFromJSON(path, block)
+		return err
+	}
+	loadReceipts := func(path string, block loader.BlockID) error {
+		_, err := loader.BlockReceiptsFromJSON(path, block)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		fixture string
+		load    func(string, loader.BlockID) error
+		block   loader.BlockID
+		fields  map[string]string
+		field   string // field of the ElementError, no error when nil fields
+	}{
+		{name: "transactions", fixture: transactionsFile, load: loadTransactions, block: loader.NumberID(blockNum)},
+		{name: "transactions by hash", fixture: transactionsFile, load: loadTransactions, block: byHash},
+		{
+			name: "unknown transaction type", fixture: transactionsFile, load: loadTransactions, block: loader.NumberID(blockNum),
+			fields: map[string]string{"type": `"0x7f"`}, field: "type",
+		},
+		{
+			name: "transaction without hash", fixture: transactionsFile, load: loadTransactions, block: loader.NumberID(blockNum),
+			fields: map[string]string{"hash": removeField}, field: "hash",
+		},
+		{
+			name: "transaction with null blockHash", fixture: transactionsFile, load: loadTransactions, block: byHash,
+			fields: map[string]string{"blockHash": "null"}, field: "blockHash",
+		},
+		{
+			name: "transaction of another block number", fixture: transactionsFile, load: loadTransactions, block: loader.NumberID(blockNum),
+			fields: map[string]string{"blockNumber": otherBlockNumber}, field: "blockNumber",
+		},
+		{
+			name: "transaction of another block hash", fixture: transactionsFile, load: loadTransactions, block: byHash,
+			fields: map[string]string{"blockHash": otherBlockHash}, field: "blockHash",
+		},
+		{name: "receipts", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum)},
+		{
+			name: "receipt with an unknown type", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum),
+			fields: map[string]string{"type": `"0x7f"`}, field: "type",
+		},
+		{
+			name: "receipt without logs", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum),
+			fields: map[string]string{"logs": removeField}, field: "logs",
+		},
+		{
+			name: "receipt without status or root", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum),
+			fields: map[string]string{"status": removeField}, field: "status",
+		},
+		{
+			name: "receipt with a null status", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum),
+			fields: map[string]string{"status": "null"}, field: "status",
+		},
+		{
+			name: "receipt with a null root", fixture: receiptsFile, load: loadReceipts, block: loader.NumberID(blockNum),
+			fields: map[string]string{"status": removeField, "root": "null"}, field: "root",
+		},
+		{
+			name: "receipt of another block", fixture: receiptsFile, load: loadReceipts, block: byHash,
+			fields: map[string]string{"blockHash": otherBlockHash}, field: "blockHash",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := writeElements(t, test.fixture, test.fields)
+			err := test.load(path, test.block)
+			if test.fields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var elementErr *loader.ElementError
+			if !errors.As(err, &elementErr) {
+				t.Fatalf("error %v, want an ElementError", err)
+			}
+			if elementErr.File != path || elementErr.Index != brokenElement || elementErr.Field != test.field {
+				t.Fatalf("error of %s element %d field %q, want %s element %d field %q",
+					elementErr.File, elementErr.Index, elementErr.Field, path, brokenElement, test.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+)
+
+const (
+	_DataDir  = "../raw-data/data"
+	_HiveDir  = "../raw-data/data/hive"
+	_BlockNum = 15209997
+)
+
+var (
+	dataDir  = flag.String("data", _DataDir, "directory with the block-N.json and block-N-receipts.json fixtures")
+	blockNum = flag.Uint64("block", _BlockNum, "block to serve")
+	hiveDir  = flag.String("hive", _HiveDir, "directory with the fixtures of the hive test chain, also served, none when empty")
+	serve    = flag.String("serve", "127.0.0.1:8545", "serve the fixtures on this address over http and WebSocket, or on this ipc socket path")
+)
+
+func main() {
+	flag.Parse()
+
+	fixture, err := mockgeth.LoadFixture(*dataDir, *blockNum)
+	cmdutil.PanicError(err)
+	fixtures := []*mockgeth.Fixture{fixture}
+	if *hiveDir != "" {
+		hive, err := mockgeth.LoadChain(*hiveDir)
+		cmdutil.PanicError(err)
+		fixtures = append(fixtures, hive...)
+	}
+	server := mockgeth.NewServer(fixtures...)
+
+	fmt.Println("Serving block", *blockNum, "on", *serve)
+	if *hiveDir != "" {
+		fmt.Println("and hive chain blocks 0 to", len(fixtures)-2)
+	}
+	if strings.Contains(*serve, "/") || strings.HasSuffix(*serve, ".ipc") {
+		l, err := net.Listen("unix", *serve)
+		cmdutil.PanicError(err)
+		cmdutil.PanicError(server.ServeIPC(l))
+		return
+	}
+	cmdutil.PanicError(http.ListenAndServe(*serve, server))
+}
@@ -0,0 +1,231 @@
+package mockgeth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+)
+
+// Fixture is the data the server returns for one block
+type Fixture struct {
+	Number      uint64
+	Hash        common.Hash
+	HeaderRlp   []byte
+	BlockRlp    []byte
+	RawReceipts [][]byte
+	BlockJSON   json.RawMessage // eth_getBlockByNumber result with full transactions
+	// ReceiptsJSON are the eth_getTransactionReceipt results by transaction hash
+	ReceiptsJSON map[common.Hash]json.RawMessage
+	// RawTransactions are the debug_getRawTransaction results by hash
+	RawTransactions map[common.Hash][]byte
+	// UnclesJSON are the eth_getUncleByBlockNumberAndIndex results by index
+	UnclesJSON []json.RawMessage
+}
+
+// LoadFixture builds the fixture of a block from the block-N.json file with
+// full transactions, the block-N-receipts.json file and, for blocks with
+// uncles, the block-N-uncles.json file in dataDir. The raw header, block and
+// receipts are the block-N-header-rlp.json, block-N-block-rlp.json and
+// block-N-raw-receipts.json files saved by archive, and are encoded from the
+// json, as Geth would return them, only when those files are missing.
+func LoadFixture(dataDir string, blockNum uint64) (*Fixture, error) {
+	blockFile := loader.FixturePath(dataDir, int(blockNum), "")
+	block, err := loader.BlockFromJSON(blockFile)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := loader.BlockReceiptsFromJSON(loader.FixturePath(dataDir, int(blockNum), "receipts"), loader.NumberID(blockNum))
+	if err != nil {
+		return nil, err
+	}
+	blockJSON, err := ioutil.ReadFile(blockFile)
+	if err != nil {
+		return nil, err
+	}
+	receiptsJSON, err := receiptsByHash(loader.FixturePath(dataDir, int(blockNum), "receipts"))
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{
+		Number:       blockNum,
+		Hash:         block.Hash,
+		BlockJSON:    blockJSON,
+		ReceiptsJSON: receiptsJSON,
+	}
+	var uncles []*types.Header
+	if len(block.Uncles) > 0 {
+		unclesFile := loader.FixturePath(dataDir, int(blockNum), "uncles")
+		var hashes []common.Hash
+		if uncles, hashes, err = loader.UnclesFromJSON(unclesFile); err != nil {
+			return nil, err
+		}
+		if len(hashes) != len(block.Uncles) {
+			return nil, fmt.Errorf("%s: %d uncles, block has %d", unclesFile, len(hashes), len(block.Uncles))
+		}
+		if fixture.UnclesJSON, err = readElements(unclesFile); err != nil {
+			return nil, err
+		}
+	}
+	fixture.RawTransactions = make(map[common.Hash][]byte, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		if fixture.RawTransactions[tx.Hash()], err = tx.MarshalBinary(); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+
+	var headerRlp, blockRlp hexutil.Bytes
+	var rawReceipts []hexutil.Bytes
+	haveHeader, err := readRaw(loader.FixturePath(dataDir, int(blockNum), "header-rlp"), &headerRlp)
+	if err != nil {
+		return nil, err
+	}
+	haveBlock, err := readRaw(loader.FixturePath(dataDir, int(blockNum), "block-rlp"), &blockRlp)
+	if err != nil {
+		return nil, err
+	}
+	haveReceipts, err := readRaw(loader.FixturePath(dataDir, int(blockNum), "raw-receipts"), &rawReceipts)
+	if err != nil {
+		return nil, err
+	}
+	if (!haveHeader || !haveBlock) && block.WithdrawalsRoot != nil {
+		return nil, fmt.Errorf("%s: post-Shanghai blocks can not be encoded", blockFile)
+	}
+
+	if fixture.HeaderRlp = headerRlp; !haveHeader {
+		if fixture.HeaderRlp, err = rlp.EncodeToBytes(block.Header); err != nil {
+			return nil, err
+		}
+	}
+	if fixture.BlockRlp = blockRlp; !haveBlock {
+		fullBlock := types.NewBlockWithHeader(block.Header).WithBody(block.Transactions, uncles)
+		if fixture.BlockRlp, err = rlp.EncodeToBytes(fullBlock); err != nil {
+			return nil, err
+		}
+	}
+	fixture.RawReceipts = make([][]byte, len(receipts))
+	if haveReceipts {
+		if len(rawReceipts) != len(receipts) {
+			return nil, fmt.Errorf("block %d: %d raw receipts, %d json receipts", blockNum, len(rawReceipts), len(receipts))
+		}
+		for i, receipt := range rawReceipts {
+			fixture.RawReceipts[i] = receipt
+		}
+		return fixture, nil
+	}
+	for i, receipt := range receipts {
+		if fixture.RawReceipts[i], err = receipt.MarshalBinary(); err != nil {
+			return nil, fmt.Errorf("receipt %d: %w", i, err)
+		}
+	}
+	return fixture, nil
+}
+
+// LoadChain builds the fixtures of a chain saved from block 0 up, such as
+// the hive test chain, up to the last block-N.json file in dataDir
+func LoadChain(dataDir string) ([]*Fixture, error) {
+	var fixtures []*Fixture
+	for n := uint64(0); ; n++ {
+		if _, err := os.Stat(loader.FixturePath(dataDir, int(n), "")); err != nil {
+			break
+		}
+		fixture, err := LoadFixture(dataDir, n)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("%s: no chain fixtures", dataDir)
+	}
+	return fixtures, nil
+}
+
+// readRaw reads a raw fixture saved by archive into v, a hex string or a
+// list of them as the node returned it. ok is false when there is no such
+// fixture.
+func readRaw(path string, v interface{}) (ok bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	return true, nil
+}
+
+// readElements reads the elements of a json array fixture
+func readElements(path string) ([]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return elements, nil
+}
+
+// blockResult returns the eth_getBlockBy* result, with only the transaction
+// hashes unless fullTx is set
+func (f *Fixture) blockResult(fullTx bool) (json.RawMessage, error) {
+	if fullTx {
+		return f.BlockJSON, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(f.BlockJSON, &fields); err != nil {
+		return nil, err
+	}
+	var txs []struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(fields["transactions"], &txs); err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash
+	}
+	var err error
+	if fields["transactions"], err = json.Marshal(hashes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// receiptsByHash reads the json receipts of a receipts fixture by their
+// transaction hash
+func receiptsByHash(path string) (map[common.Hash]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var receipts []json.RawMessage
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	byHash := make(map[common.Hash]json.RawMessage, len(receipts))
+	for i, receipt := range receipts {
+		var dec struct {
+			TxHash common.Hash `json:"transactionHash"`
+		}
+		if err := json.Unmarshal(receipt, &dec); err != nil {
+			return nil, fmt.Errorf("%s: element %d: %w", path, i, err)
+		}
+		byHash[dec.TxHash] = receipt
+	}
+	return byHash, nil
+}
@@ -0,0 +1,569 @@
+// Package mockgeth is a fake Geth JSON-RPC server that serves blocks from json
+// fixtures, so the rpc client and the verify checks can run without a node.
+// Faults can be injected to check how the client handles a bad node.
+package mockgeth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/websocket"
+
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// Fault changes the server's answer to calls of a method
+type Fault struct {
+	Method      string        // method the fault applies to, every method when empty
+	WrongBytes  bool          // flip the last byte of hex results
+	TruncateHex bool          // drop the last hex digit of hex results
+	Error       *rpc.Error    // answer with this error instead of the result
+	Delay       time.Duration // wait before answering
+	Drop        bool          // close the connection without answering
+	// DropTransaction removes the last transaction from raw blocks and
+	// keeps their header, so the block hash still matches
+	DropTransaction bool
+	// WrongBloom sets a bit of the logs bloom of the last raw receipt that
+	// no log of the receipt sets
+	WrongBloom bool
+	// SetFields replaces fields of json object results, such as blocks
+	SetFields map[string]json.RawMessage
+	// ReverseBatch answers the calls of a batch in reverse order
+	ReverseBatch bool
+	// DuplicateID gives the last response of a batch the id of the first
+	DuplicateID bool
+	// Times is the number of http requests the fault applies to, after
+	// which the server answers normally again. Every request when zero.
+	Times int
+}
+
+func (f Fault) applies(method string) bool {
+	return f.Method == "" || f.Method == method
+}
+
+// Server answers JSON-RPC calls, single or batched, from its fixtures
+type Server struct {
+	mu       sync.Mutex
+	byNumber map[uint64]*Fixture
+	byHash   map[common.Hash]*Fixture
+	latest   uint64
+	faults   []Fault
+	used     []int // number of requests each fault applied to
+	legacy   bool  // serve the raw methods under their pre-1.11 names
+
+	inFlight int        // messages being handled
+	idle     *sync.Cond // signaled when no message is being handled
+}
+
+// NewServer creates a server for the given fixtures
+func NewServer(fixtures ...*Fixture) *Server {
+	s := &Server{
+		byNumber: make(map[uint64]*Fixture),
+		byHash:   make(map[common.Hash]*Fixture),
+	}
+	s.idle = sync.NewCond(&s.mu)
+	for _, f := range fixtures {
+		s.byNumber[f.Number] = f
+		s.byHash[f.Hash] = f
+		if f.Number > s.latest {
+			s.latest = f.Number
+		}
+	}
+	return s
+}
+
+// Start serves http and WebSocket on a local port until the returned server
+// is closed
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// SetFaults replaces the injected faults, no faults restores normal answers
+func (s *Server) SetFaults(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+	s.used = make([]int, len(faults))
+}
+
+// SetLegacyNames makes the server answer to the raw header and block methods
+// under their old names, debug_getHeaderRlp and debug_getBlockRlp, as Geth
+// did before 1.11, instead of debug_getRawHeader and debug_getRawBlock
+func (s *Server) SetLegacyNames(legacy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.legacy = legacy
+}
+
+// Wait waits until the messages being handled are answered, so that late
+// messages of an earlier client do not count against new faults
+func (s *Server) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.inFlight > 0 {
+		s.idle.Wait()
+	}
+}
+
+// handling counts a message as being handled until the returned function is
+// called
+func (s *Server) handling() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight++
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.inFlight--; s.inFlight == 0 {
+			s.idle.Broadcast()
+		}
+	}
+}
+
+// takeFaults returns the faults for an http request with the given calls,
+// and counts them against their Times
+func (s *Server) takeFaults(requests []request) []Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var faults []Fault
+	for i, f := range s.faults {
+		if f.Times > 0 && s.used[i] >= f.Times {
+			continue
+		}
+		for _, req := range requests {
+			if f.applies(req.Method) {
+				faults = append(faults, f)
+				s.used[i]++
+				break
+			}
+		}
+	}
+	return faults
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpc.Error      `json:"error,omitempty"`
+}
+
+// ServeHTTP answers calls posted over http, and WebSocket upgrades on the
+// same port as Geth does
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	response, drop := s.HandleMessage(r.Context(), body)
+	if drop {
+		dropConnection(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// HandleMessage answers an encoded call or batch, as any transport receives
+// it. drop is set when the connection should be closed without an answer.
+func (s *Server) HandleMessage(ctx context.Context, body []byte) (answer []byte, drop bool) {
+	defer s.handling()()
+
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var requests []request
+	if batch {
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return encode(errorResponse(-32600, "invalid request")), false
+		}
+	} else {
+		requests = make([]request, 1)
+		if err := json.Unmarshal(body, &requests[0]); err != nil {
+			return encode(errorResponse(-32700, "parse error")), false
+		}
+	}
+
+	faults := s.takeFaults(requests)
+	var delay time.Duration
+	for _, f := range faults {
+		if f.Drop {
+			return nil, true
+		}
+		if f.Delay > delay {
+			delay = f.Delay
+		}
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, true
+		}
+	}
+
+	responses := make([]response, len(requests))
+	for i, req := range requests {
+		responses[i] = s.answer(req, faults)
+	}
+	if batch {
+		for _, f := range faults {
+			if f.ReverseBatch {
+				for i, j := 0, len(responses)-1; i < j; i, j = i+1, j-1 {
+					responses[i], responses[j] = responses[j], responses[i]
+				}
+			}
+			if f.DuplicateID && len(responses) > 1 {
+				responses[len(responses)-1].ID = responses[0].ID
+			}
+		}
+		return encode(responses), false
+	}
+	return encode(responses[0]), false
+}
+
+// answer a single call, with the faults for its method applied
+func (s *Server) answer(req request, faults []Fault) response {
+	res := response{Jsonrpc: "2.0", ID: req.ID}
+	s.mu.Lock()
+	legacy := s.legacy
+	s.mu.Unlock()
+	res.Result, res.Error = s.handle(req.Method, req.Params, legacy)
+	for _, f := range faults {
+		if !f.applies(req.Method) {
+			continue
+		}
+		if f.Error != nil {
+			res.Result, res.Error = nil, f.Error
+		}
+		if res.Error != nil {
+			continue
+		}
+		if f.WrongBytes {
+			res.Result = mapHex(res.Result, flipLastByte)
+		}
+		if len(f.SetFields) > 0 {
+			res.Result = setFields(res.Result, f.SetFields)
+		}
+		if f.WrongBloom {
+			res.Result = wrongBloom(res.Result)
+		}
+		if f.DropTransaction {
+			res.Result = mapHex(res.Result, dropLastTransaction)
+		}
+		if f.TruncateHex {
+			res.Result = mapHex(res.Result, func(s string) string { return s[:len(s)-1] })
+		}
+	}
+	// a missing block is a null result, which omitempty would drop
+	if res.Result == nil && res.Error == nil {
+		res.Result = json.RawMessage("null")
+	}
+	return res
+}
+
+// handle returns the result of a call as Geth would, with the legacy or the
+// current names of the raw methods
+func (s *Server) handle(method string, params []json.RawMessage, legacy bool) (interface{}, *rpc.Error) {
+	headerMethod, blockMethod := rpc.RawHeaderMethod.Modern, rpc.RawBlockMethod.Modern
+	if legacy {
+		headerMethod, blockMethod = rpc.RawHeaderMethod.Legacy, rpc.RawBlockMethod.Legacy
+	}
+	notFound := &rpc.Error{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", method)}
+
+	switch method {
+	case "eth_blockNumber":
+		return hexutil.Uint64(s.latest), nil
+	case "eth_chainId":
+		return hexutil.Uint64(1), nil
+	case "eth_getBlockByHash":
+		var hash common.Hash
+		if err := param(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		fixture := s.byHash[hash]
+		if fixture == nil {
+			return nil, nil
+		}
+		return s.blockResult(fixture, params)
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if err := param(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		for _, f := range s.byNumber {
+			if receipt, ok := f.ReceiptsJSON[hash]; ok {
+				return receipt, nil
+			}
+		}
+		return nil, nil
+	case "debug_getRawTransaction":
+		var hash common.Hash
+		if err := param(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		for _, f := range s.byNumber {
+			if tx, ok := f.RawTransactions[hash]; ok {
+				return hexutil.Encode(tx), nil
+			}
+		}
+		return nil, nil
+	case headerMethod, blockMethod, "debug_getRawReceipts", "eth_getBlockByNumber", "eth_getUncleByBlockNumberAndIndex":
+	default:
+		return nil, notFound
+	}
+
+	var (
+		f      *Fixture
+		number uint64
+		err    *rpc.Error
+	)
+	if legacy && (method == headerMethod || method == blockMethod) {
+		f, number, err = s.numberParam(params)
+	} else {
+		byHash := method != "eth_getBlockByNumber" && method != "eth_getUncleByBlockNumberAndIndex"
+		f, number, err = s.blockParam(params, byHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch method {
+	case headerMethod:
+		if f == nil {
+			return nil, &rpc.Error{Code: -32000, Message: fmt.Sprintf("header #%d not found", number)}
+		}
+		return hexutil.Encode(f.HeaderRlp), nil
+	case blockMethod:
+		if f == nil {
+			return nil, &rpc.Error{Code: -32000, Message: fmt.Sprintf("block #%d not found", number)}
+		}
+		return hexutil.Encode(f.BlockRlp), nil
+	case "debug_getRawReceipts":
+		if f == nil {
+			return nil, &rpc.Error{Code: -32000, Message: fmt.Sprintf("block #%d not found", number)}
+		}
+		receipts := make([]string, len(f.RawReceipts))
+		for i, receipt := range f.RawReceipts {
+			receipts[i] = hexutil.Encode(receipt)
+		}
+		return receipts, nil
+	case "eth_getBlockByNumber":
+		if f == nil {
+			return nil, nil
+		}
+		return s.blockResult(f, params)
+	case "eth_getUncleByBlockNumberAndIndex":
+		var index hexutil.Uint64
+		if err := param(params, 1, &index); err != nil {
+			return nil, err
+		}
+		if f == nil || uint64(index) >= uint64(len(f.UnclesJSON)) {
+			return nil, nil
+		}
+		return f.UnclesJSON[index], nil
+	}
+	return nil, notFound
+}
+
+// numberParam looks up the block in the first param, a plain json number as
+// the legacy raw methods take
+func (s *Server) numberParam(params []json.RawMessage) (*Fixture, uint64, *rpc.Error) {
+	var number uint64
+	if err := param(params, 0, &number); err != nil {
+		return nil, 0, err
+	}
+	return s.byNumber[number], number, nil
+}
+
+// blockParam looks up the block in the first param, a hex number or a tag,
+// or a block hash when byHash is set, as a BlockNumberOrHash param
+func (s *Server) blockParam(params []json.RawMessage, byHash bool) (*Fixture, uint64, *rpc.Error) {
+	var value string
+	if err := param(params, 0, &value); err != nil {
+		return nil, 0, err
+	}
+	switch value {
+	case "latest", "safe", "finalized", "pending":
+		return s.byNumber[s.latest], s.latest, nil
+	case "earliest":
+		return s.byNumber[0], 0, nil
+	}
+	if byHash && len(value) == 2+2*common.HashLength {
+		hash := common.HexToHash(value)
+		if f := s.byHash[hash]; f != nil {
+			return f, f.Number, nil
+		}
+		return nil, 0, &rpc.Error{Code: -32000, Message: fmt.Sprintf("header for hash %s not found", hash)}
+	}
+	number, err := hexutil.DecodeUint64(value)
+	if err != nil {
+		return nil, 0, &rpc.Error{Code: -32602, Message: fmt.Sprintf("invalid argument 0: %v", err)}
+	}
+	return s.byNumber[number], number, nil
+}
+
+// blockResult returns a block, with full transactions when the second param
+// is true
+func (s *Server) blockResult(f *Fixture, params []json.RawMessage) (interface{}, *rpc.Error) {
+	var fullTx bool
+	if err := param(params, 1, &fullTx); err != nil {
+		return nil, err
+	}
+	result, err := f.blockResult(fullTx)
+	if err != nil {
+		return nil, &rpc.Error{Code: -32000, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// param decodes a required param
+func param(params []json.RawMessage, i int, v interface{}) *rpc.Error {
+	if len(params) <= i {
+		return &rpc.Error{Code: -32602, Message: fmt.Sprintf("missing value for required argument %d", i)}
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return &rpc.Error{Code: -32602, Message: fmt.Sprintf("invalid argument %d: %v", i, err)}
+	}
+	return nil
+}
+
+// mapHex applies fn to a hex string result, or to each string of a list
+func mapHex(result interface{}, fn func(string) string) interface{} {
+	switch r := result.(type) {
+	case string:
+		return fn(r)
+	case []string:
+		mapped := make([]string, len(r))
+		for i := range r {
+			mapped[i] = fn(r[i])
+		}
+		return mapped
+	}
+	return result
+}
+
+// flipLastByte changes the last byte of a hex string
+func flipLastByte(hex string) string {
+	b, err := hexutil.Decode(hex)
+	if err != nil || len(b) == 0 {
+		return hex
+	}
+	b[len(b)-1] ^= 0xff
+	return hexutil.Encode(b)
+}
+
+// setFields replaces fields of a json object result
+func setFields(result interface{}, values map[string]json.RawMessage) interface{} {
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		return result
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return result
+	}
+	for name, value := range values {
+		fields[name] = value
+	}
+	return json.RawMessage(encode(fields))
+}
+
+// dropLastTransaction removes the last transaction from a hex rlp-encoded
+// block
+// wrongBloom sets an unset bit of the bloom of the last receipt of a raw
+// receipts result
+func wrongBloom(result interface{}) interface{} {
+	hexes, ok := result.([]string)
+	if !ok || len(hexes) == 0 {
+		return result
+	}
+	last := hexes[len(hexes)-1]
+	b, err := hexutil.Decode(last)
+	if err != nil {
+		return result
+	}
+	var receipt types.Receipt
+	if err := receipt.UnmarshalBinary(b); err != nil {
+		return result
+	}
+	for bit := uint(0); bit < types.BloomBitLength; bit++ {
+		if receipt.Bloom[bit/8]&(1<<(bit%8)) == 0 {
+			receipt.Bloom[bit/8] |= 1 << (bit % 8)
+			break
+		}
+	}
+	encoded, err := receipt.MarshalBinary()
+	if err != nil {
+		return result
+	}
+	mapped := append([]string{}, hexes...)
+	mapped[len(mapped)-1] = hexutil.Encode(encoded)
+	return mapped
+}
+
+func dropLastTransaction(hex string) string {
+	b, err := hexutil.Decode(hex)
+	if err != nil {
+		return hex
+	}
+	var parts []rlp.RawValue
+	if err := rlp.DecodeBytes(b, &parts); err != nil || len(parts) < 2 {
+		return hex
+	}
+	var txs []rlp.RawValue
+	if err := rlp.DecodeBytes(parts[1], &txs); err != nil || len(txs) == 0 {
+		return hex
+	}
+	if parts[1], err = rlp.EncodeToBytes(txs[:len(txs)-1]); err != nil {
+		return hex
+	}
+	encoded, err := rlp.EncodeToBytes(parts)
+	if err != nil {
+		return hex
+	}
+	return hexutil.Encode(encoded)
+}
+
+// dropConnection closes the connection without writing a response
+func dropConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic("mockgeth: connection can not be dropped")
+	}
+	conn, _, err := hijacker.Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+// errorResponse is the answer to a request that could not be read
+func errorResponse(code int, message string) response {
+	return response{Jsonrpc: "2.0", ID: json.RawMessage("null"), Error: &rpc.Error{Code: code, Message: message}}
+}
+
+func encode(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,85 @@
+package mockgeth
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// ServeIPC answers calls on the connections accepted by l, as Geth does on
+// its geth.ipc socket: json values one after the other on each connection.
+// It returns when l is closed.
+func (s *Server) ServeIPC(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveIPCConn(conn)
+	}
+}
+
+func (s *Server) serveIPCConn(conn net.Conn) {
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dec := json.NewDecoder(conn)
+	for {
+		var body json.RawMessage
+		if err := dec.Decode(&body); err != nil {
+			return
+		}
+		response, drop := s.HandleMessage(ctx, body)
+		if drop {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// StartIPC serves on a Unix socket at path until the returned listener is
+// closed
+func (s *Server) StartIPC(path string) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go s.ServeIPC(l)
+	return l, nil
+}
+
+// serveWebSocket answers calls sent as text messages over WebSocket, one
+// call or batch per message
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		_, body, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		response, drop := s.HandleMessage(r.Context(), body)
+		if drop {
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, response); err != nil {
+			return
+		}
+	}
+}
+
+// WebSocketURL returns the ws:// url of a server from Start
+func WebSocketURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
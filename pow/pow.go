@@ -0,0 +1,278 @@
+// Package pow verifies the Ethash proof-of-work seal of pre-merge headers
+// with light hashimoto: dataset items computed on the fly from the
+// verification cache of the epoch, which the ethash package of go-ethereum
+// builds and keeps on disk for the next runs.
+package pow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// from go-ethereum/consensus/ethash/algorithm.go
+const (
+	datasetInitBytes   = 1 << 30 // bytes in the dataset at genesis
+	datasetGrowthBytes = 1 << 23 // dataset growth per epoch
+	epochLength        = 30000   // blocks per epoch
+	mixBytes           = 128     // width of the mix
+	hashBytes          = 64      // hash length in bytes
+	hashWords          = 16      // number of 32 bit ints in a hash
+	datasetParents     = 256     // number of parents of each dataset element
+	loopAccesses       = 64      // number of accesses in the hashimoto loop
+
+	// cacheRevision is the revision in the name of the cache files ethash
+	// writes, which start with the two words of cacheMagic
+	cacheRevision = 23
+	cacheMagic0   = 0xbaddcafe
+	cacheMagic1   = 0xfee1dead
+
+	// cachesInMem is the number of epoch caches kept in memory
+	cachesInMem = 2
+)
+
+// two256 is 2^256, divided by the difficulty for the target of the result
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// sealHasher computes seal hashes, which do not depend on the engine's state
+var sealHasher = ethash.NewFaker()
+
+// Verifier checks the seal of proof-of-work headers, with the caches of the
+// last epochs it used in memory
+type Verifier struct {
+	mu     sync.Mutex
+	dir    string
+	temp   bool                // dir is a temporary directory, removed by Close
+	caches map[uint64][]uint32 // by epoch
+	epochs []uint64            // epochs of caches, least recently used first
+}
+
+// DefaultDir returns the directory for the caches under the user cache
+// directory, or "" when there is none
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "validate-ethereum-data", "ethash")
+}
+
+// NewVerifier returns a verifier with the caches stored in dir, or in a
+// temporary directory removed by Close when dir is ""
+func NewVerifier(dir string) *Verifier {
+	return &Verifier{dir: dir, caches: make(map[uint64][]uint32)}
+}
+
+// VerifySeal checks the seal of a proof-of-work header on its own: light
+// hashimoto over the seal hash and nonce must give the mixHash of the header
+// and a result at most 2^256 divided by the difficulty. The other header
+// rules, which need the parent, are not checked.
+func (v *Verifier) VerifySeal(header *types.Header) error {
+	if header.Difficulty == nil || header.Difficulty.Sign() <= 0 {
+		return fmt.Errorf("invalid difficulty %v, a sealed header has a positive difficulty", header.Difficulty)
+	}
+	number := header.Number.Uint64()
+	cache, err := v.cache(number / epochLength)
+	if err != nil {
+		return err
+	}
+	digest, result := hashimotoLight(datasetSize(number/epochLength), cache, v.SealHash(header).Bytes(), header.Nonce.Uint64())
+	if !bytes.Equal(header.MixDigest[:], digest) {
+		return fmt.Errorf("invalid mix digest: hashimoto gives %s, mixHash is %s", common.BytesToHash(digest), header.MixDigest)
+	}
+	target := new(big.Int).Div(two256, header.Difficulty)
+	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		return fmt.Errorf("invalid proof-of-work: result %#x above the target of difficulty %d", result, header.Difficulty)
+	}
+	return nil
+}
+
+// SealHash returns the hash of a header without its mixHash and nonce, the
+// hash the miner searched a nonce for
+func (v *Verifier) SealHash(header *types.Header) common.Hash {
+	return sealHasher.SealHash(header)
+}
+
+// Close removes the temporary cache directory, if any
+func (v *Verifier) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.caches, v.epochs = make(map[uint64][]uint32), nil
+	if !v.temp {
+		return nil
+	}
+	v.temp = false
+	return os.RemoveAll(v.dir)
+}
+
+// cache returns the verification cache of an epoch. ethash builds it into
+// the cache directory, or it is read back from an earlier run.
+func (v *Verifier) cache(epoch uint64) ([]uint32, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if cache, ok := v.caches[epoch]; ok {
+		v.use(epoch)
+		return cache, nil
+	}
+	if v.dir == "" {
+		dir, err := os.MkdirTemp("", "ethash")
+		if err != nil {
+			return nil, err
+		}
+		v.dir, v.temp = dir, true
+	}
+	ethash.MakeCache(epoch*epochLength, v.dir)
+	cache, err := readCache(v.dir, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.epochs) == cachesInMem {
+		delete(v.caches, v.epochs[0])
+		v.epochs = v.epochs[1:]
+	}
+	v.caches[epoch] = cache
+	v.use(epoch)
+	return cache, nil
+}
+
+// use marks the cache of an epoch as the most recently used
+func (v *Verifier) use(epoch uint64) {
+	for i, e := range v.epochs {
+		if e == epoch {
+			v.epochs = append(v.epochs[:i], v.epochs[i+1:]...)
+			break
+		}
+	}
+	v.epochs = append(v.epochs, epoch)
+}
+
+// readCache reads the cache file of an epoch that ethash wrote in dir, in
+// the byte order of the machine, named after the revision and the seed
+func readCache(dir string, epoch uint64) ([]uint32, error) {
+	order, suffix := binary.ByteOrder(binary.LittleEndian), ""
+	if !isLittleEndian() {
+		order, suffix = binary.BigEndian, ".be"
+	}
+	seed := ethash.SeedHash(epoch*epochLength + 1)
+	path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", cacheRevision, seed[:8], suffix))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ethash cache of epoch %d: %w", epoch, err)
+	}
+	if len(data) < 8 || order.Uint32(data) != cacheMagic0 || order.Uint32(data[4:]) != cacheMagic1 || (len(data)-8)%hashBytes != 0 {
+		return nil, fmt.Errorf("ethash cache of epoch %d: %s is not a cache file", epoch, path)
+	}
+	cache := make([]uint32, (len(data)-8)/4)
+	for i := range cache {
+		cache[i] = order.Uint32(data[8+i*4:])
+	}
+	return cache, nil
+}
+
+// isLittleEndian reports whether the machine is little endian, the byte
+// order ethash dumps the cache in
+func isLittleEndian() bool {
+	n := uint32(0x01020304)
+	return *(*byte)(unsafe.Pointer(&n)) == 0x04
+}
+
+// datasetSize returns the size in bytes of the full dataset of an epoch,
+// which hashimoto indexes into even when the items are computed from the
+// cache: the largest size below the linear growth whose number of mixes is
+// a prime
+func datasetSize(epoch uint64) uint64 {
+	size := datasetInitBytes + datasetGrowthBytes*epoch - mixBytes
+	for !new(big.Int).SetUint64(size / mixBytes).ProbablyPrime(1) {
+		size -= 2 * mixBytes
+	}
+	return size
+}
+
+// fnv is the FNV-1 inspired mixing function of Ethash, which uses xor
+// instead of the FNV-1a multiplication order
+func fnv(a, b uint32) uint32 {
+	return a*0x01000193 ^ b
+}
+
+func fnvHash(mix []uint32, data []uint32) {
+	for i := 0; i < len(mix); i++ {
+		mix[i] = mix[i]*0x01000193 ^ data[i]
+	}
+}
+
+func toWords(dst []uint32, src []byte) {
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint32(src[i*4:])
+	}
+}
+
+func toBytes(dst []byte, src []uint32) {
+	for i, word := range src {
+		binary.LittleEndian.PutUint32(dst[i*4:], word)
+	}
+}
+
+// datasetItem computes the dataset item at index from the cache: a cache
+// item mixed with datasetParents pseudo-random cache items
+func datasetItem(cache []uint32, index uint32) []uint32 {
+	rows := uint32(len(cache) / hashWords)
+
+	buf := make([]byte, hashBytes)
+	mix := make([]uint32, hashWords)
+	copy(mix, cache[(index%rows)*hashWords:])
+	mix[0] ^= index
+	toBytes(buf, mix)
+	toWords(mix, crypto.Keccak512(buf))
+
+	for i := uint32(0); i < datasetParents; i++ {
+		parent := fnv(index^i, mix[i%hashWords]) % rows
+		fnvHash(mix, cache[parent*hashWords:])
+	}
+	toBytes(buf, mix)
+	toWords(mix, crypto.Keccak512(buf))
+	return mix
+}
+
+// hashimotoLight computes the mix digest and the result of a seal hash and
+// nonce, with the dataset items of size bytes of dataset computed from the
+// cache
+func hashimotoLight(size uint64, cache []uint32, hash []byte, nonce uint64) ([]byte, []byte) {
+	rows := uint32(size / mixBytes)
+
+	seed := make([]byte, 40)
+	copy(seed, hash)
+	binary.LittleEndian.PutUint64(seed[32:], nonce)
+	seed = crypto.Keccak512(seed)
+	seedHead := binary.LittleEndian.Uint32(seed)
+
+	mix := make([]uint32, mixBytes/4)
+	for i := range mix {
+		mix[i] = binary.LittleEndian.Uint32(seed[i%16*4:])
+	}
+	temp := make([]uint32, len(mix))
+	for i := 0; i < loopAccesses; i++ {
+		parent := fnv(uint32(i)^seedHead, mix[i%len(mix)]) % rows
+		for j := uint32(0); j < mixBytes/hashBytes; j++ {
+			copy(temp[j*hashWords:], datasetItem(cache, 2*parent+j))
+		}
+		fnvHash(mix, temp)
+	}
+	for i := 0; i < len(mix); i += 4 {
+		mix[i/4] = fnv(fnv(fnv(mix[i], mix[i+1]), mix[i+2]), mix[i+3])
+	}
+	mix = mix[:len(mix)/4]
+
+	digest := make([]byte, common.HashLength)
+	toBytes(digest, mix)
+	return digest, crypto.Keccak256(seed, digest)
+}
@@ -0,0 +1,105 @@
+package pow_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/KohdMonkey/validate-ethereum-data/pow"
+)
+
+var emptyRoot = common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// mainnetGenesis and mainnetBlock1 are the first two headers of mainnet.
+// Block 1 is the first mined block, of epoch 0, whose cache builds quickly.
+func mainnetGenesis() *types.Header {
+	return &types.Header{
+		ParentHash:  common.Hash{},
+		UncleHash:   types.EmptyUncleHash,
+		Root:        common.HexToHash("0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544"),
+		TxHash:      emptyRoot,
+		ReceiptHash: emptyRoot,
+		Difficulty:  big.NewInt(0x400000000),
+		Number:      big.NewInt(0),
+		GasLimit:    5000,
+		Extra:       common.FromHex("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa"),
+		Nonce:       types.EncodeNonce(0x42),
+	}
+}
+
+func mainnetBlock1() *types.Header {
+	return &types.Header{
+		ParentHash:  params.MainnetGenesisHash,
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.HexToAddress("0x05a56e2d52c817161883f50c441c3228cfe54d9f"),
+		Root:        common.HexToHash("0xd67e4d450343046425ae4271474353857ab860dbc0a1dde64b41b5cd3a532bf3"),
+		TxHash:      emptyRoot,
+		ReceiptHash: emptyRoot,
+		Difficulty:  big.NewInt(0x3ff800000),
+		Number:      big.NewInt(1),
+		GasLimit:    5000,
+		Time:        1438269988,
+		Extra:       common.FromHex("0x476574682f76312e302e302f6c696e75782f676f312e342e32"),
+		MixDigest:   common.HexToHash("0x969b900de27b6ac6a67742365dd65f55a0526c41fd18e1b16f1a1215c2e66f59"),
+		Nonce:       types.EncodeNonce(0x539bd4979fef1ec4),
+	}
+}
+
+func TestVerifySeal(t *testing.T) {
+	genesis, block1 := mainnetGenesis(), mainnetBlock1()
+	if hash := genesis.Hash(); hash != params.MainnetGenesisHash {
+		t.Fatalf("genesis hashes to %s, want %s", hash, params.MainnetGenesisHash)
+	}
+	if hash, want := block1.Hash(), common.HexToHash("0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"); hash != want {
+		t.Fatalf("block 1 hashes to %s, want %s", hash, want)
+	}
+
+	dir := t.TempDir()
+	verifier := pow.NewVerifier(dir)
+	defer verifier.Close()
+
+	wrongNonce := mainnetBlock1()
+	wrongNonce.Nonce = types.EncodeNonce(block1.Nonce.Uint64() + 1)
+	wrongMix := mainnetBlock1()
+	wrongMix.MixDigest = common.Hash{}
+	// a higher difficulty changes the seal hash
+	wrongDifficulty := mainnetBlock1()
+	wrongDifficulty.Difficulty = big.NewInt(0x400000000)
+	zeroDifficulty := mainnetBlock1()
+	zeroDifficulty.Difficulty = new(big.Int)
+
+	tests := []struct {
+		name   string
+		header *types.Header
+		err    string // part of the error, no error when empty
+	}{
+		{name: "mined block", header: block1},
+		{name: "wrong nonce", header: wrongNonce, err: "invalid mix digest"},
+		{name: "wrong mix digest", header: wrongMix, err: "invalid mix digest"},
+		{name: "wrong difficulty", header: wrongDifficulty, err: "invalid mix digest"},
+		{name: "zero difficulty", header: zeroDifficulty, err: "invalid difficulty 0"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := verifier.VerifySeal(test.header)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+
+	// the seal is checked the same with the cache of the earlier run
+	again := pow.NewVerifier(dir)
+	defer again.Close()
+	if err := again.VerifySeal(block1); err != nil {
+		t.Fatalf("with the cache on disk: %v", err)
+	}
+}
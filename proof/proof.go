@@ -0,0 +1,140 @@
+// Package proof builds and checks Merkle proofs that a transaction is
+// included in the transactions trie of a block.
+package proof
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/KohdMonkey/validate-ethereum-data/hashing"
+)
+
+// TransactionProof is the proof that a transaction is stored at
+// rlp(TransactionIndex) in the transactions trie with root TransactionsRoot
+type TransactionProof struct {
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionsRoot common.Hash     `json:"transactionsRoot"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+	TransactionHash  common.Hash     `json:"transactionHash"`
+	Key              hexutil.Bytes   `json:"key"`
+	Value            hexutil.Bytes   `json:"value"`
+	Proof            []hexutil.Bytes `json:"proof"`
+}
+
+// nodeList collects the trie nodes of a proof, from the root down
+type nodeList []hexutil.Bytes
+
+func (n *nodeList) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+func (n *nodeList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// TransactionKey returns the key of the i'th transaction in the trie
+func TransactionKey(index uint64) []byte {
+	key, _ := rlp.EncodeToBytes(uint(index))
+	return key
+}
+
+// ProveTransaction builds the transactions trie of a block and returns the
+// proof for the transaction at index
+func ProveTransaction(blockNum uint64, txs []*types.Transaction, index uint64) (*TransactionProof, error) {
+	if index >= uint64(len(txs)) {
+		return nil, fmt.Errorf("transaction index %d out of range, block has %d transactions", index, len(txs))
+	}
+
+	tr := hashing.NewTrie()
+	root := hashing.DeriveSha(types.Transactions(txs), tr)
+
+	key := TransactionKey(index)
+	var nodes nodeList
+	if err := tr.Prove(key, 0, &nodes); err != nil {
+		return nil, err
+	}
+	value, err := txs[index].MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionProof{
+		BlockNumber:      hexutil.Uint64(blockNum),
+		TransactionsRoot: root,
+		TransactionIndex: hexutil.Uint64(index),
+		TransactionHash:  txs[index].Hash(),
+		Key:              key,
+		Value:            value,
+		Proof:            nodes,
+	}, nil
+}
+
+// Verify checks the proof against the transactions root from a trusted
+// header, and checks that the proven value is the claimed transaction
+func (p *TransactionProof) Verify(transactionsRoot common.Hash) error {
+	if p.TransactionsRoot != transactionsRoot {
+		return fmt.Errorf("proof is for transactions root %s, header has %s", p.TransactionsRoot, transactionsRoot)
+	}
+	if !bytes.Equal(p.Key, TransactionKey(uint64(p.TransactionIndex))) {
+		return fmt.Errorf("proof key %s is not rlp(%d)", p.Key, p.TransactionIndex)
+	}
+
+	proofDb := memorydb.New()
+	for _, node := range p.Proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	value, err := trie.VerifyProof(transactionsRoot, p.Key, proofDb)
+	if err != nil {
+		return fmt.Errorf("invalid proof: %w", err)
+	}
+	if value == nil {
+		return fmt.Errorf("proof shows there is no transaction at index %d", p.TransactionIndex)
+	}
+	if !bytes.Equal(value, p.Value) {
+		return fmt.Errorf("proven value does not match the transaction in the proof")
+	}
+
+	// the value is the canonical encoding of the transaction, check it
+	// decodes and hashes to the claimed transaction hash
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(value); err != nil {
+		return fmt.Errorf("proven value is not a transaction: %w", err)
+	}
+	if tx.Hash() != p.TransactionHash {
+		return fmt.Errorf("proven transaction has hash %s, proof claims %s", tx.Hash(), p.TransactionHash)
+	}
+	return nil
+}
+
+// WriteFile writes the proof as json to path
+func (p *TransactionProof) WriteFile(path string) error {
+	out, err := json.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// ReadFile reads a proof written by WriteFile
+func ReadFile(path string) (*TransactionProof, error) {
+	byteValue, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	var p TransactionProof
+	if err := json.Unmarshal(byteValue, &p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
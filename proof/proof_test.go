@@ -0,0 +1,135 @@
+package proof_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/proof"
+)
+
+const (
+	dataDir  = "../raw-data/data"
+	blockNum = 15209997
+)
+
+// loadBlock loads the transactions of the fixture block and the
+// transactions root of its header
+func loadBlock(t *testing.T) ([]*types.Transaction, common.Hash) {
+	t.Helper()
+	txs, err := loader.BlockTransactionsFromJSON(loader.FixturePath(dataDir, blockNum, "transactions"), loader.NumberID(blockNum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := loader.HeaderFromJSON(loader.FixturePath(dataDir, blockNum, "header"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txs, header.TxHash
+}
+
+func TestProveTransaction(t *testing.T) {
+	txs, root := loadBlock(t)
+	for _, index := range []uint64{0, uint64(len(txs) / 2), uint64(len(txs) - 1)} {
+		p, err := proof.ProveTransaction(blockNum, txs, index)
+		if err != nil {
+			t.Fatalf("transaction %d: %v", index, err)
+		}
+		if p.TransactionsRoot != root {
+			t.Fatalf("transaction %d: proof root %s, header has %s", index, p.TransactionsRoot, root)
+		}
+		if p.TransactionHash != txs[index].Hash() {
+			t.Fatalf("transaction %d: proof of %s, want %s", index, p.TransactionHash, txs[index].Hash())
+		}
+		// the proof is checked as the tx-proof command reads it back
+		path := filepath.Join(t.TempDir(), "proof.json")
+		if err := p.WriteFile(path); err != nil {
+			t.Fatal(err)
+		}
+		read, err := proof.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := read.Verify(root); err != nil {
+			t.Fatalf("transaction %d: %v", index, err)
+		}
+	}
+
+	if _, err := proof.ProveTransaction(blockNum, txs, uint64(len(txs))); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("index past the last transaction: error %v, want out of range", err)
+	}
+}
+
+func TestVerifyInvalidProof(t *testing.T) {
+	txs, root := loadBlock(t)
+	const index = 7
+
+	tests := []struct {
+		name   string
+		change func(p *proof.TransactionProof)
+		root   common.Hash
+		err    string // part of the error
+	}{
+		{
+			name: "tampered proof node",
+			change: func(p *proof.TransactionProof) {
+				node := p.Proof[len(p.Proof)-1]
+				node[len(node)-1] ^= 0xff
+			},
+			root: root,
+			err:  "invalid proof",
+		},
+		{
+			name: "wrong root",
+			root: common.HexToHash("0x01"),
+			err:  "proof is for transactions root",
+		},
+		{
+			name:   "wrong root in the proof",
+			change: func(p *proof.TransactionProof) { p.TransactionsRoot = common.HexToHash("0x01") },
+			root:   common.HexToHash("0x01"),
+			err:    "invalid proof",
+		},
+		{
+			name:   "key of another index",
+			change: func(p *proof.TransactionProof) { p.Key = proof.TransactionKey(index + 1) },
+			root:   root,
+			err:    "is not rlp(7)",
+		},
+		{
+			name: "proof claims another index",
+			change: func(p *proof.TransactionProof) {
+				p.TransactionIndex++
+				p.Key = proof.TransactionKey(index + 1)
+			},
+			root: root,
+			err:  "invalid proof",
+		},
+		{
+			name:   "proof claims another transaction",
+			change: func(p *proof.TransactionProof) { p.TransactionHash = txs[index+1].Hash() },
+			root:   root,
+			err:    "proven transaction has hash",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			p, err := proof.ProveTransaction(blockNum, txs, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.change != nil {
+				test.change(p)
+			}
+			err = p.Verify(test.root)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
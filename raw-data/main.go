@@ -1,19 +1,25 @@
 package main
 
 import (
-	"bytes"
-	"encoding/hex"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"math/big"
+	"math/bits"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/ethereum/go-ethereum/trie"
+
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/pow"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
 )
 
 const (
@@ -23,270 +29,565 @@ const (
 	_ReceiptsRoot = "0x5ff308f613dd6b9cc880622fe638c4099c38fc85d02db7c738952618380360fd"
 
 	_HeaderHash = "0x868248867378bf14da3923ba2242e00a97154f390956ee5d5f7793f97920c047"
-	// BlockHash A block's hash is just the hash of its header.
-	_BlockHash = "0x868248867378bf14da3923ba2242e00a97154f390956ee5d5f7793f97920c047"
 )
 
-// RequestData struct to hold parameters for rpc call
-type RequestData struct {
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params"`
-	ID      int           `json:"id"`
-	Jsonrpc string        `json:"jsonrpc"`
+var (
+	rpcFlags = rpc.RegisterFlags(flag.CommandLine, strconv.Itoa(_BlockNum))
+	dataDir  = flag.String("data", _DataDir, "directory with the json fixtures")
+	workers  = flag.Int("workers", 8, "number of blocks verified at once in range mode")
+	failFast = flag.Bool("failfast", false, "stop at the first block that does not pass in range mode")
+	txHash   = flag.String("tx", "", "also verify this transaction of the block with debug_getRawTransaction")
+	genesis  = flag.String("genesis", "mainnet", "genesis.json with the chain config of the forks, or mainnet")
+	seal     = flag.Bool("seal", true, "verify the Ethash seal of proof-of-work headers")
+	cacheDir = flag.String("ethashdir", pow.DefaultDir(), "directory of the Ethash verification caches, \"\" to keep them in memory")
+
+	// schedule is the fork schedule of -genesis, loaded by main
+	schedule verify.Schedule
+	// sealVerifier checks proof-of-work headers with the Ethash caches of
+	// -ethashdir, nil without -seal
+	sealVerifier *pow.Verifier
+)
+
+// Target is the block being verified and the data it is checked against
+type Target struct {
+	BlockNum             uint64
+	HeaderFromJson       *types.Header
+	ReceiptsFromJson     []*types.Receipt // nil when there is no receipts fixture
+	ExpectedHash         common.Hash
+	ExpectedReceiptsRoot common.Hash
+	Data                 *rpc.BlockData // raw data fetched from the client
+	Uncles               *rpc.UncleData // nil when the block has no uncles
+	Parent               *types.Header  // raw parent header, nil for the genesis block
+	// ParentTD is the total difficulty of the parent, from the
+	// totalDifficulty the node reports for the block, nil when it does not
+	ParentTD *big.Int
+
+	// HeaderFile, ReceiptsFile and TransactionsFile are the fixtures used,
+	// if any
+	HeaderFile       string
+	ReceiptsFile     string
+	TransactionsFile string
 }
 
-// ResponseData struct to hold response data with a single rlp-encoded string
-type ResponseData struct {
-	Jsonrpc string `json:"jsonrpc"`
-	ID      int    `json:"id"`
-	Result  string `json:"result"`
+// fileExists reports whether a fixture is present
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-// ResponseDataArray struct to hold response data with an array of rlp-encoded strings
-type ResponseDataArray struct {
-	Jsonrpc string   `json:"jsonrpc"`
-	ID      int      `json:"id"`
-	Result  []string `json:"result"`
+// ResolveTarget fetch the selected block from the client in one batch and
+// load what it is checked against. Blocks selected by hash or tag are looked
+// up by number first. A selected hash is the expected hash.
+func ResolveTarget(ctx context.Context, client *rpc.Client, block rpc.BlockSelector) Target {
+	var blockNum uint64
+	if block.Number != nil {
+		blockNum = *block.Number
+	} else {
+		nodeHeader, _, err := client.HeaderByBlock(ctx, block)
+		if err != nil {
+			ExitRPCError(err)
+		}
+		blockNum = nodeHeader.Number.Uint64()
+	}
+	data, err := client.FetchBlockData(ctx, blockNum)
+	if err != nil {
+		ExitRPCError(err)
+	}
+	target, err := NewTarget(data)
+	cmdutil.PanicError(err)
+	if target.Uncles, err = FetchUncles(ctx, client, data); err != nil {
+		ExitRPCError(err)
+	}
+	if target.Parent, err = FetchParent(ctx, client, data); err != nil {
+		ExitRPCError(err)
+	}
+
+	fmt.Println("Block", target.BlockNum, "from", client.Endpoint())
+	if target.HeaderFile != "" {
+		fmt.Println("Header fixture: ", target.HeaderFile)
+	}
+	if target.ReceiptsFile != "" {
+		fmt.Println("Receipts fixture: ", target.ReceiptsFile)
+	}
+	if target.TransactionsFile != "" {
+		fmt.Println("Transactions fixture: ", target.TransactionsFile)
+	}
+	fmt.Println("Raw methods: ", client.MethodName(rpc.RawHeaderMethod), client.MethodName(rpc.RawBlockMethod))
+	if block.Hash != nil {
+		target.ExpectedHash = *block.Hash
+	}
+	return target
 }
 
-// ReceiptsFromJSON load receipts from json file
-func ReceiptsFromJSON() []*types.Receipt {
-	receiptsFile := _DataDir + "/block-" + strconv.Itoa(_BlockNum) + "-receipts.json"
-	jsonFile, err := os.Open(receiptsFile)
+// NewTarget load what the fetched block is checked against. Fixtures in the
+// data directory are preferred, the json header from the client is used for
+// blocks without one. The expected hash is the known hash of the fixture
+// block, or the hash the client reports.
+func NewTarget(data *rpc.BlockData) (Target, error) {
+	target := Target{
+		BlockNum:             data.Number,
+		HeaderFromJson:       data.Header,
+		ExpectedHash:         data.Hash,
+		ExpectedReceiptsRoot: data.Header.ReceiptHash,
+		Data:                 data,
+	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening file: %v\n", err)
-		os.Exit(1)
+	var err error
+	headerFile := loader.FixturePath(*dataDir, int(target.BlockNum), "header")
+	if fileExists(headerFile) {
+		target.HeaderFile = headerFile
+		target.HeaderFromJson, err = loader.HeaderFromJSON(headerFile)
+		if err != nil {
+			return target, err
+		}
+		target.ExpectedReceiptsRoot = target.HeaderFromJson.ReceiptHash
+	}
+	receiptsFile := loader.FixturePath(*dataDir, int(target.BlockNum), "receipts")
+	if fileExists(receiptsFile) {
+		target.ReceiptsFile = receiptsFile
+		target.ReceiptsFromJson, err = loader.BlockReceiptsFromJSON(receiptsFile, loader.NumberID(target.BlockNum))
+		if err != nil {
+			return target, err
+		}
+	}
+
+	if txsFile := loader.FixturePath(*dataDir, int(target.BlockNum), "transactions"); fileExists(txsFile) {
+		target.TransactionsFile = txsFile
 	}
-	defer jsonFile.Close()
 
-	byteValue, err := ioutil.ReadAll(jsonFile)
+	td, err := loader.BlockTotalDifficulty(data.BlockJSON)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading file: %v\n", err)
-		os.Exit(1)
+		return target, err
+	}
+	if td != nil && target.HeaderFromJson.Difficulty != nil {
+		target.ParentTD = new(big.Int).Sub(td, target.HeaderFromJson.Difficulty)
 	}
 
-	var receipts []*types.Receipt
-	json.Unmarshal(byteValue, &receipts)
-	return receipts
+	if target.BlockNum == _BlockNum {
+		target.ExpectedHash = common.HexToHash(_HeaderHash)
+		target.ExpectedReceiptsRoot = common.HexToHash(_ReceiptsRoot)
+	}
+	return target, nil
 }
 
-// HeaderFromJSON load header from json file
-func HeaderFromJSON() types.Header {
-	transactionsFile := _DataDir + "/block-" + strconv.Itoa(_BlockNum) + "-header.json"
-	jsonFile, err := os.Open(transactionsFile)
-	PanicError(err)
-	defer jsonFile.Close()
-
-	byteValue, err := ioutil.ReadAll(jsonFile)
-	PanicError(err)
-
-	var header types.Header
-	json.Unmarshal(byteValue, &header)
-	return header
+// FetchUncles fetch the uncles of the raw block by index, and the ancestors
+// they are checked against. It returns nil for blocks without uncles, and
+// for raw blocks that do not decode, which the block check reports.
+func FetchUncles(ctx context.Context, client *rpc.Client, data *rpc.BlockData) (*rpc.UncleData, error) {
+	block, err := loader.SplitRawBlock(data.BlockRlp)
+	if err != nil {
+		return nil, nil
+	}
+	uncles, err := block.UncleHeaders()
+	if err != nil || len(uncles) == 0 {
+		return nil, nil
+	}
+	return client.FetchUncleData(ctx, data.Number, len(uncles))
 }
 
-func PanicError(err error) {
+// FetchParent fetch the raw header of the parent of the block, which its
+// header rules are checked against, and check that it is the parent. It
+// returns nil for the genesis block, and when the node does not have the
+// parent.
+func FetchParent(ctx context.Context, client *rpc.Client, data *rpc.BlockData) (*types.Header, error) {
+	if data.Number == 0 {
+		return nil, nil
+	}
+	headerBytes, err := client.GetHeaderRlp(ctx, data.Number-1)
+	if errors.Is(err, rpc.ErrBlockNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	parent, err := loader.BytesToHeader(headerBytes)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("parent header: %w", err)
 	}
+	if hash := parent.Hash(); hash != data.Header.ParentHash {
+		return nil, fmt.Errorf("parent header hashes to %s, parentHash is %s", hash, data.Header.ParentHash)
+	}
+	return parent, nil
 }
 
-func ExitError(err string) {
-	fmt.Println(err)
-	os.Exit(1)
+// ExitRPCError print the error with a hint for the errors a node commonly
+// returns, then exit
+func ExitRPCError(err error) {
+	var httpErr *rpc.HTTPError
+	var mismatch *verify.MismatchError
+	switch {
+	case errors.As(err, &mismatch):
+		cmdutil.ExitError(formatMismatch(mismatch, ""))
+	case errors.Is(err, rpc.ErrMethodNotFound):
+		cmdutil.ExitError(err.Error() + "\n(is the debug namespace enabled on the node? see --http.api)")
+	case errors.Is(err, rpc.ErrPruned):
+		cmdutil.ExitError(err.Error() + "\n(the node no longer keeps this block, use an archive node)")
+	case errors.Is(err, rpc.ErrBlockNotFound):
+		cmdutil.ExitError(err.Error() + "\n(the node does not have this block, is it synced?)")
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized:
+		cmdutil.ExitError(err.Error() + "\n(check -jwtsecret or the -header credentials)")
+	}
+	cmdutil.ExitError(err.Error())
 }
 
-// ParseResponse decode http response into ResponseData struct
-func ParseResponse(response *http.Response) ResponseData {
-	var resData ResponseData
-	json.NewDecoder(response.Body).Decode(&resData)
+// formatMismatch formats a mismatch with one line per field that differs
+func formatMismatch(mismatch *verify.MismatchError, indent string) string {
+	if len(mismatch.Diffs) == 0 {
+		return indent + mismatch.Error()
+	}
+	lines := []string{indent + mismatch.Message + ":"}
+	for _, diff := range mismatch.Diffs {
+		lines = append(lines, indent+"    "+diff.String())
+	}
+	return strings.Join(lines, "\n")
+}
 
-	return resData
+// VerifyRawHeader verify rlp-encoded header data from the client
+func VerifyRawHeader(target Target) {
+	fmt.Println("Verifying raw header... ")
+	headerHash, err := verify.CheckRawHeader(target.Data.HeaderRlp, target.HeaderFromJson, target.ExpectedHash)
+	if err != nil {
+		ExitRPCError(err)
+	}
+
+	fmt.Println("header hash matches")
+	fmt.Println("Hash from client: ", headerHash.String())
+	fmt.Println("Expected hash: ", target.ExpectedHash.String())
 }
 
-// ParseResponseArray decode http response into ResponseDataArray struct
-func ParseResponseArray(response *http.Response) ResponseDataArray {
-	var resData ResponseDataArray
-	json.NewDecoder(response.Body).Decode(&resData)
+// VerifyRawBlock verify rlp-encoded block data from the client, and its body
+// against the roots in its header
+func VerifyRawBlock(target Target) {
+	fmt.Println("Verifying raw block... ")
+	// A block's hash is just the hash of its header.
+	blockHash, bodyChecks, err := verify.CheckRawBlock(target.Data.BlockRlp, target.ExpectedHash)
+	if err != nil {
+		ExitRPCError(err)
+	}
 
-	return resData
+	fmt.Println("block hash matches")
+	fmt.Println("Hash from client: ", blockHash.String())
+	fmt.Println("Expected hash: ", target.ExpectedHash.String())
+
+	// the body is not covered by the hash, each root is checked on its own
+	failed := false
+	for _, check := range bodyChecks {
+		if check.Err != nil {
+			fmt.Printf("%s: FAIL %v\n", check.Name, check.Err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s matches body: %s\n", check.Name, check.Computed.String())
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
 
-// ResultToByteArray parse hex-encoded string into a byte array
-func ResultToByteArray(resString string) []byte {
-	data, err := hex.DecodeString(resString[2:])
-	PanicError(err)
-	return data
+// VerifyUncles verify the uncles of the raw block against the uncles from
+// the client and the ommer rules
+func VerifyUncles(target Target) {
+	fmt.Println("Verifying uncles... ")
+	block, err := loader.SplitRawBlock(target.Data.BlockRlp)
+	cmdutil.PanicError(err)
+
+	failed := false
+	for _, check := range verify.CheckUncles(block, target.Uncles) {
+		if check.Err != nil {
+			var mismatch *verify.MismatchError
+			if errors.As(check.Err, &mismatch) {
+				fmt.Printf("%s: FAIL\n%s\n", check.Name, formatMismatch(mismatch, "    "))
+			} else {
+				fmt.Printf("%s: FAIL %v\n", check.Name, check.Err)
+			}
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: ok\n", check.Name)
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println(len(target.Uncles.Uncles), "uncles match")
 }
 
-// BytesToHeader decode rlp-encoded header
-func BytesToHeader(dataBytes []byte) *types.Header {
-	var header *types.Header
-	err := rlp.DecodeBytes(dataBytes, &header)
-	PanicError(err)
+// VerifyRawReceipts verify rlp-encoded receipts from the client
+func VerifyRawReceipts(target Target) {
+	fmt.Println("Verifying raw receipts... ")
+	receiptsRoot, err := verify.CheckRawReceipts(target.Data.RawReceipts, target.ReceiptsFromJson, target.ExpectedReceiptsRoot)
+	if err != nil {
+		ExitRPCError(err)
+	}
 
-	return header
+	fmt.Println("receipts root matches")
+	fmt.Println("root from client: ", receiptsRoot.String())
+	fmt.Println("Expected root: ", target.ExpectedReceiptsRoot.String())
 }
 
-// BytesToBlock decode rlp-encoded block
-func BytesToBlock(dataBytes []byte) *types.Block {
-	var block *types.Block
-	err := rlp.DecodeBytes(dataBytes, &block)
-	PanicError(err)
+// VerifyHeaderRules verify the header against the consensus rules of its
+// fork, given its parent
+func VerifyHeaderRules(target Target) {
+	fmt.Println("Verifying header rules... ")
+	failed := false
+	for _, check := range verify.CheckHeaderRules(schedule, target.HeaderFromJson, target.Parent, target.ParentTD) {
+		if check.Err != nil {
+			fmt.Printf("%s: FAIL %v\n", check.Name, check.Err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: ok\n", check.Name)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
 
-	return block
+// Merged reports whether the block comes after the merge, by the total
+// difficulty of its parent, or by its difficulty when the node does not
+// report the total difficulty
+func (t Target) Merged() bool {
+	return schedule.IsMerged(t.HeaderFromJson, t.ParentTD)
 }
 
-// ExecuteRequest make a rpc call to the client with the given request data
-func ExecuteRequest(data RequestData) *http.Response {
-	payloadBytes, err := json.Marshal(data)
-	PanicError(err)
+// hasSeal reports whether the block is a mined proof-of-work block, with a
+// seal to verify: neither the genesis block nor a block after the merge
+func hasSeal(target Target) bool {
+	return target.BlockNum > 0 && !target.Merged()
+}
 
-	body := bytes.NewReader(payloadBytes)
+// PrintFork print the fork of the block, and whether it is the terminal
+// proof-of-work block
+func PrintFork(target Target) {
+	consensus := "proof of work"
+	if target.Merged() {
+		consensus = "proof of stake"
+	}
+	fmt.Printf("fork %s, %s\n", schedule.Fork(target.HeaderFromJson, target.Merged()), consensus)
+	if target.ParentTD == nil {
+		return
+	}
+	td := new(big.Int).Add(target.ParentTD, target.HeaderFromJson.Difficulty)
+	if schedule.IsTerminal(td, target.ParentTD) {
+		fmt.Printf("terminal proof-of-work block: total difficulty %d reaches %d\n",
+			td, schedule.Config.TerminalTotalDifficulty)
+	}
+}
 
-	req, err := http.NewRequest("POST", "http://127.0.0.1:8545/", body)
-	PanicError(err)
+// VerifySeal verify the Ethash seal of a proof-of-work header, which does
+// not need the parent
+func VerifySeal(target Target) {
+	fmt.Println("Verifying Ethash seal... ")
+	header := target.HeaderFromJson
+	if err := sealVerifier.VerifySeal(header); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
 
-	req.Header.Set("Content-Type", "application/json")
+	fmt.Println("seal matches")
+	fmt.Println("Seal hash: ", sealVerifier.SealHash(header).String())
+	fmt.Println("Mix digest: ", header.MixDigest.String())
+	fmt.Printf("Nonce: %#x\n", header.Nonce.Uint64())
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	PanicError(err)
+// VerifyLogsBloom verify the blooms of the rlp-encoded receipts from the
+// client, and the logsBloom of the header, against the logs
+func VerifyLogsBloom(target Target) {
+	fmt.Println("Verifying logs bloom... ")
+	bloom, err := verify.CheckLogsBloom(target.Data.RawReceipts, target.ReceiptsFromJson, target.HeaderFromJson.Bloom)
+	var bloomErr *verify.BloomError
+	if errors.As(err, &bloomErr) {
+		lines := []string{"logs bloom does not match the logs:"}
+		for _, diff := range bloomErr.Diffs {
+			lines = append(lines, "    "+diff.String())
+		}
+		cmdutil.ExitError(strings.Join(lines, "\n"))
+	}
+	if err != nil {
+		ExitRPCError(err)
+	}
 
-	return resp
+	fmt.Println("logs bloom matches the logs of", len(target.Data.RawReceipts), "receipts")
+	fmt.Println("Bits set in bloom: ", bloomBits(bloom))
 }
 
-// VerifyRawHeader verify rlp-encoded header data from the client
-func VerifyRawHeader() {
-	fmt.Println("Verifying raw header... ")
-	data := RequestData{
-		Method:  "debug_getHeaderRlp",
-		Params:  []interface{}{_BlockNum},
-		ID:      1,
-		Jsonrpc: "2.0",
+// bloomBits counts the bits set in a bloom
+func bloomBits(bloom types.Bloom) int {
+	n := 0
+	for _, b := range bloom {
+		n += bits.OnesCount8(b)
 	}
+	return n
+}
 
-	// fetch rlp-encoded header and parse response into bytes
-	resp := ExecuteRequest(data)
-	defer resp.Body.Close()
-
-	resData := ParseResponse(resp)
-	headerBytes := ResultToByteArray(resData.Result)
+// VerifyReceiptConsistency verify that the receipts from the client fit
+// the transactions of the block and its header
+func VerifyReceiptConsistency(target Target) {
+	fmt.Println("Verifying receipt consistency... ")
+	if err := checkReceiptConsistency(target); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
 
-	//load header bytes from json file and check if rlp-encoded bytes match
-	headerFromJson := HeaderFromJSON()
-	headerBytesFromJson, err := rlp.EncodeToBytes(&headerFromJson)
-	PanicError(err)
+	fmt.Println("receipts fit the", len(target.Data.RawReceipts), "transactions of the block")
+	fmt.Println("Gas used: ", target.HeaderFromJson.GasUsed)
+}
 
-	if !bytes.Equal(headerBytesFromJson, headerBytes) {
-		ExitError("raw header from json does not match raw header from rpc")
+// checkReceiptConsistency check the raw receipts, and the receipts fixture
+// if any, against the transactions of the block and its header
+func checkReceiptConsistency(target Target) error {
+	txs, err := blockTransactions(target)
+	if err != nil {
+		return err
 	}
+	receipts, err := loader.BytesToReceipts(target.Data.RawReceipts)
+	if err != nil {
+		return err
+	}
+	return verify.CheckReceiptConsistency(target.HeaderFromJson, target.ExpectedHash, txs, receipts, target.ReceiptsFromJson)
+}
 
-	// construct header from raw bytes and calculate the hash
-	header := BytesToHeader(headerBytes)
-	headerHashStr := header.Hash().String()
-
-	if headerHashStr != _HeaderHash {
-		ExitError("header hash does not match")
+// VerifyTransactionHashes verify the hash and transactionIndex of each
+// transaction of the transactions fixture, and the transactionHash of the
+// receipts
+func VerifyTransactionHashes(target Target) {
+	fmt.Println("Verifying transaction hashes... ")
+	if err := checkTransactionHashes(target); err != nil {
+		cmdutil.ExitError(err.Error())
 	}
 
-	fmt.Println("header hash matches")
-	fmt.Println("Hash from client: ", headerHashStr)
-	fmt.Println("Expected hash: ", _HeaderHash)
+	fmt.Println("transaction hashes and indexes match")
 }
 
-// VerifyRawBlock verify rlp-encoded block data from the client
-func VerifyRawBlock() {
-	fmt.Println("Verifying raw block... ")
-	data := RequestData{
-		Method:  "debug_getBlockRlp",
-		Params:  []interface{}{_BlockNum},
-		ID:      1,
-		Jsonrpc: "2.0",
+// checkTransactionHashes check the transactions fixture against the hashes
+// and indexes it claims, and against the receipts fixture if any
+func checkTransactionHashes(target Target) error {
+	id := loader.NumberID(target.BlockNum)
+	txs, err := loader.BlockTransactionsFromJSON(target.TransactionsFile, id)
+	if err != nil {
+		return err
 	}
+	refs, err := loader.BlockTxRefsFromJSON(target.TransactionsFile, id)
+	if err != nil {
+		return err
+	}
+	return verify.CheckTransactionHashes(txs, refs, target.ReceiptsFromJson)
+}
 
-	// fetch rlp-encoded block and parse response into bytes
-	resp := ExecuteRequest(data)
-	defer resp.Body.Close()
-
-	// construct block from raw bytes and calculate the hash
-	resData := ParseResponse(resp)
-	blockBytes := ResultToByteArray(resData.Result)
-	block := BytesToBlock(blockBytes)
-	blockHashStr := block.Hash().String()
+// VerifyRawTransaction verify a binary-encoded transaction of the block from
+// the client, against the transactions fixture or the raw block
+func VerifyRawTransaction(ctx context.Context, client *rpc.Client, target Target, hash common.Hash) {
+	fmt.Println("Verifying raw transaction... ")
+	txs, err := blockTransactions(target)
+	cmdutil.PanicError(err)
+	var txFromJson *types.Transaction
+	for _, tx := range txs {
+		if tx.Hash() == hash {
+			txFromJson = tx
+		}
+	}
+	if txFromJson == nil {
+		cmdutil.ExitError(fmt.Sprintf("transaction %s is not in block %d", hash, target.BlockNum))
+	}
 
-	if blockHashStr != _BlockHash {
-		ExitError("block hash does not match")
+	txHashFromClient, err := verify.VerifyRawTransaction(ctx, client, hash, txFromJson)
+	if err != nil {
+		ExitRPCError(err)
 	}
 
-	fmt.Println("block hash matches")
-	fmt.Println("Hash from client: ", blockHashStr)
-	fmt.Println("Expected hash: ", _BlockHash)
+	fmt.Println("transaction hash matches")
+	fmt.Println("Hash from client: ", txHashFromClient.String())
+	fmt.Println("Expected hash: ", hash.String())
 }
 
-// VerifyRawReceipts verify rlp-encoded receipts from the client
-func VerifyRawReceipts() {
-	fmt.Println("Verifying raw receipts... ")
-	BlockNumString := fmt.Sprintf("0x%x", _BlockNum)
-	data := RequestData{
-		Method:  "debug_getRawReceipts",
-		Params:  []interface{}{BlockNumString},
-		ID:      1,
-		Jsonrpc: "2.0",
-	}
-	// fetch rlp-encoded receipts and parse response into receipts
-	resp := ExecuteRequest(data)
-	defer resp.Body.Close()
-
-	resData := ParseResponseArray(resp)
-	encodedReceipts := resData.Result
-
-	numReceipts := len(encodedReceipts)
-	receipts := make([]*types.Receipt, numReceipts)
-	receiptsBytesArr := make([][]byte, numReceipts)
-	for i := 0; i < numReceipts; i++ {
-		receipts[i] = new(types.Receipt)
-		receiptHex := encodedReceipts[i][2:]
-		receiptBytes := common.FromHex(receiptHex)
-		receiptsBytesArr[i] = receiptBytes
-
-		err := receipts[i].UnmarshalBinary(receiptBytes)
-		if err != nil {
-			fmt.Println("error unmarshalling receipt")
-		}
+// blockTransactions returns the transactions fixture of the block, or the
+// transactions of the raw block when there is none
+func blockTransactions(target Target) ([]*types.Transaction, error) {
+	if target.TransactionsFile != "" {
+		return loader.BlockTransactionsFromJSON(target.TransactionsFile, loader.NumberID(target.BlockNum))
+	}
+	block, err := loader.SplitRawBlock(target.Data.BlockRlp)
+	if err != nil {
+		return nil, err
 	}
+	return block.DecodeTransactions()
+}
 
-	// load receipts from json and check if receipts matches
-	receiptsFromJson := ReceiptsFromJSON()
-	for i := 0; i < numReceipts; i++ {
-		receiptBinaryFromJson, err := receiptsFromJson[i].MarshalBinary()
-		PanicError(err)
+func main() {
+	flag.Parse()
 
-		if !bytes.Equal(receiptsBytesArr[i], receiptBinaryFromJson) {
-			errStr := fmt.Sprintf(
-				"receipt %d from json does not match receipt from rpc", i)
-			ExitError(errStr)
-		}
+	var err error
+	if schedule, err = verify.LoadSchedule(*genesis); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	if *seal {
+		sealVerifier = pow.NewVerifier(*cacheDir)
+		defer sealVerifier.Close()
 	}
 
-	// construct trie using receipts and retrieve receipt root hash
-	hasher := trie.NewStackTrie(nil)
-	treeHash := types.DeriveSha(types.Receipts(receipts), hasher)
-	treeHashStr := treeHash.String()
-
-	if treeHashStr != _ReceiptsRoot {
-		ExitError("receipts root does not match")
+	config, err := rpcFlags.Config()
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	client := rpc.NewClient(config)
+	ctx, cancel := rpcFlags.Context()
+	defer cancel()
+
+	// range mode, for -block N..M
+	from, to, err := rpcFlags.Range()
+	if errors.Is(err, rpc.ErrRangeTooLarge) {
+		cmdutil.ExitError(err.Error())
+	}
+	if err == nil && from != to {
+		fmt.Printf("Verifying blocks %d to %d from %s\n", from, to, client.Endpoint())
+		results := VerifyRange(ctx, client, from, to, *workers, *failFast)
+		missing, chainErr := CheckRangeChain(results)
+		PrintSummary(results, missing, chainErr)
+		if chainErr != nil {
+			os.Exit(1)
+		}
+		for _, r := range results {
+			if !r.Passed() {
+				os.Exit(1)
+			}
+		}
+		return
 	}
 
-	fmt.Println("receipts root matches")
-	fmt.Println("root from client: ", treeHashStr)
-	fmt.Println("Expected root: ", _ReceiptsRoot)
-}
+	block, err := rpcFlags.Block()
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
 
-func main() {
-	VerifyRawHeader()
+	target := ResolveTarget(ctx, client, block)
+	fmt.Println("----------------------------------------------------")
+	VerifyRawHeader(target)
 	fmt.Println("----------------------------------------------------")
-	VerifyRawBlock()
+	PrintFork(target)
+	if target.Parent != nil {
+		VerifyHeaderRules(target)
+	} else {
+		fmt.Println("No parent header, the header rules are not checked")
+	}
+	if sealVerifier != nil && hasSeal(target) {
+		fmt.Println("----------------------------------------------------")
+		VerifySeal(target)
+	}
+	fmt.Println("----------------------------------------------------")
+	VerifyRawBlock(target)
+	if target.Uncles != nil {
+		fmt.Println("----------------------------------------------------")
+		VerifyUncles(target)
+	}
 	fmt.Println("----------------------------------------------------")
-	VerifyRawReceipts()
+	VerifyRawReceipts(target)
+	fmt.Println("----------------------------------------------------")
+	VerifyLogsBloom(target)
+	fmt.Println("----------------------------------------------------")
+	VerifyReceiptConsistency(target)
+	if target.TransactionsFile != "" {
+		fmt.Println("----------------------------------------------------")
+		VerifyTransactionHashes(target)
+	}
+	if *txHash != "" {
+		fmt.Println("----------------------------------------------------")
+		VerifyRawTransaction(ctx, client, target, common.HexToHash(*txHash))
+	}
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+// BlockResult is the outcome of verifying one block. Err is set when the
+// block could not be fetched or loaded, and the checks did not run.
+type BlockResult struct {
+	BlockNum uint64
+	Err      error
+	Checks   []verify.Check
+	Skipped  bool          // not verified after a fail-fast stop
+	Header   *types.Header // raw header from the client, nil when not fetched
+}
+
+// Passed reports whether the block was verified and every check passed
+func (r BlockResult) Passed() bool {
+	if r.Err != nil || r.Skipped {
+		return false
+	}
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// RunChecks runs the raw header, block, receipts, bloom and receipt
+// consistency checks on a target, with one result for each root of the block
+// body, the uncle checks for blocks with uncles, the transaction hash check
+// for blocks with a transactions fixture, the header rules for blocks with
+// a parent, by the rules of the fork, and the Ethash seal of proof-of-work
+// blocks with a parent unless -seal=false
+func RunChecks(target Target) []verify.Check {
+	_, headerErr := verify.CheckRawHeader(target.Data.HeaderRlp, target.HeaderFromJson, target.ExpectedHash)
+	_, bodyChecks, blockErr := verify.CheckRawBlock(target.Data.BlockRlp, target.ExpectedHash)
+	_, receiptsErr := verify.CheckRawReceipts(target.Data.RawReceipts, target.ReceiptsFromJson, target.ExpectedReceiptsRoot)
+	results := []verify.Check{
+		{Name: "header", Err: headerErr},
+		{Name: "block", Err: blockErr},
+	}
+	for _, check := range bodyChecks {
+		results = append(results, verify.Check{Name: "block " + check.Name, Err: check.Err})
+	}
+	if block, err := loader.SplitRawBlock(target.Data.BlockRlp); err == nil && target.Uncles != nil {
+		for _, check := range verify.CheckUncles(block, target.Uncles) {
+			results = append(results, verify.Check{Name: check.Name, Err: check.Err})
+		}
+	}
+	_, bloomErr := verify.CheckLogsBloom(target.Data.RawReceipts, target.ReceiptsFromJson, target.HeaderFromJson.Bloom)
+	results = append(results,
+		verify.Check{Name: "receipts", Err: receiptsErr},
+		verify.Check{Name: "logs bloom", Err: bloomErr},
+		verify.Check{Name: "receipt consistency", Err: checkReceiptConsistency(target)},
+	)
+	if target.TransactionsFile != "" {
+		results = append(results, verify.Check{Name: "transaction hashes", Err: checkTransactionHashes(target)})
+	}
+	if target.Parent != nil {
+		for _, check := range verify.CheckHeaderRules(schedule, target.HeaderFromJson, target.Parent, target.ParentTD) {
+			results = append(results, verify.Check{Name: check.Name, Err: check.Err})
+		}
+	}
+	if sealVerifier != nil && hasSeal(target) {
+		results = append(results, verify.Check{Name: "seal", Err: sealVerifier.VerifySeal(target.HeaderFromJson)})
+	}
+	return results
+}
+
+// VerifyBlock fetch a block and run every check on it
+func VerifyBlock(ctx context.Context, client *rpc.Client, blockNum uint64) BlockResult {
+	result := BlockResult{BlockNum: blockNum}
+	data, err := client.FetchBlockData(ctx, blockNum)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	// a header that does not decode fails the header check, and breaks the
+	// chain of the range
+	result.Header, _ = loader.BytesToHeader(data.HeaderRlp)
+	target, err := NewTarget(data)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if target.Uncles, err = FetchUncles(ctx, client, data); err != nil {
+		result.Err = err
+		return result
+	}
+	if target.Parent, err = FetchParent(ctx, client, data); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Checks = RunChecks(target)
+	return result
+}
+
+// VerifyRange verify blocks from to to with a pool of workers. Results are
+// printed, and returned, in block order. With failFast no new blocks are
+// started once a block did not pass.
+func VerifyRange(ctx context.Context, client *rpc.Client, from, to uint64, workers int, failFast bool) []BlockResult {
+	count := int(to - from + 1)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > count {
+		workers = count
+	}
+
+	results := make([]BlockResult, count)
+	done := make([]chan struct{}, count)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var stopped int32
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				blockNum := from + uint64(i)
+				if atomic.LoadInt32(&stopped) != 0 {
+					results[i] = BlockResult{BlockNum: blockNum, Skipped: true}
+				} else {
+					results[i] = VerifyBlock(ctx, client, blockNum)
+					if failFast && !results[i].Passed() {
+						atomic.StoreInt32(&stopped, 1)
+					}
+				}
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < count; i++ {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	// print each result as soon as all blocks before it are done
+	for i := range results {
+		<-done[i]
+		printBlockResult(results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// printBlockResult print one line per block, with the reasons it failed
+func printBlockResult(r BlockResult) {
+	switch {
+	case r.Skipped:
+		return
+	case r.Err != nil:
+		fmt.Printf("block %d: ERROR %v\n", r.BlockNum, r.Err)
+	case r.Passed():
+		fmt.Printf("block %d: ok\n", r.BlockNum)
+	default:
+		fmt.Printf("block %d: FAIL\n", r.BlockNum)
+		for _, check := range r.Checks {
+			var mismatch *verify.MismatchError
+			switch {
+			case check.Err == nil:
+			case errors.As(check.Err, &mismatch):
+				fmt.Printf("    %s: %s\n", check.Name, strings.TrimSpace(formatMismatch(mismatch, "    ")))
+			default:
+				fmt.Printf("    %s: %v\n", check.Name, check.Err)
+			}
+		}
+	}
+}
+
+// CheckRangeChain check that the raw headers of the range form a chain, each
+// one the parent of the next. Blocks without a header, not fetched or skipped
+// after a fail-fast stop, split the range, and the links across them are not
+// checked. It returns the number of these blocks and the first break.
+func CheckRangeChain(results []BlockResult) (int, error) {
+	missing, start := 0, 0
+	var segment []*types.Header
+	checkSegment := func() error {
+		err := verify.CheckHeaderChain(segment)
+		var chainBreak *verify.ChainBreak
+		if errors.As(err, &chainBreak) {
+			chainBreak.Index += start
+		}
+		return err
+	}
+	for i, r := range results {
+		if r.Header != nil {
+			if len(segment) == 0 {
+				start = i
+			}
+			segment = append(segment, r.Header)
+			continue
+		}
+		missing++
+		if err := checkSegment(); err != nil {
+			return missing, err
+		}
+		segment = nil
+	}
+	return missing, checkSegment()
+}
+
+// PrintSummary print the number of blocks that passed, failed, errored or
+// were skipped, the failure reasons of each check with their count, and
+// whether the headers form a chain, with missing blocks it was not checked
+// across
+func PrintSummary(results []BlockResult, missing int, chainErr error) {
+	var passed, failed, errored, skipped int
+	reasons := make(map[string]map[string]int) // check name to reason counts
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Err != nil:
+			errored++
+			countReason(reasons, "fetch", r.Err)
+		case r.Passed():
+			passed++
+		default:
+			failed++
+			for _, check := range r.Checks {
+				if check.Err != nil {
+					countReason(reasons, check.Name, check.Err)
+				}
+			}
+		}
+	}
+
+	fmt.Println("----------------------------------------------------")
+	fmt.Printf("%d blocks: %d passed, %d failed, %d errored", len(results), passed, failed, errored)
+	if skipped > 0 {
+		fmt.Printf(", %d skipped after fail-fast", skipped)
+	}
+	fmt.Println()
+	switch {
+	case chainErr != nil:
+		fmt.Println("header chain: FAIL", chainErr)
+	case missing == len(results):
+		fmt.Println("header chain: not checked, no header was fetched")
+	case missing > 0:
+		fmt.Printf("header chain: each fetched header is the parent of the next, not checked across %d blocks without a header\n", missing)
+	default:
+		fmt.Println("header chain: each header is the parent of the next")
+	}
+
+	checks := make([]string, 0, len(reasons))
+	for name := range reasons {
+		checks = append(checks, name)
+	}
+	sort.Strings(checks)
+	for _, name := range checks {
+		fmt.Printf("%s:\n", name)
+		messages := make([]string, 0, len(reasons[name]))
+		for reason := range reasons[name] {
+			messages = append(messages, reason)
+		}
+		sort.Strings(messages)
+		for _, reason := range messages {
+			fmt.Printf("    %dx %s\n", reasons[name][reason], reason)
+		}
+	}
+}
+
+// countReason count an error under its kind for the typed rpc errors, whose
+// messages differ from block to block, without the fields that differ for
+// mismatches, and under its message otherwise
+func countReason(reasons map[string]map[string]int, check string, err error) {
+	if reasons[check] == nil {
+		reasons[check] = make(map[string]int)
+	}
+	reason := err.Error()
+	var mismatch *verify.MismatchError
+	if errors.As(err, &mismatch) {
+		reason = mismatch.Message
+	}
+	for _, kind := range []error{rpc.ErrBlockNotFound, rpc.ErrMethodNotFound, rpc.ErrPruned} {
+		if errors.Is(err, kind) {
+			reason = kind.Error()
+			break
+		}
+	}
+	reasons[check][reason]++
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+const (
+	hiveDir = "data/hive"
+	// rangeFrom and rangeTo are the blocks of the hive chain verified
+	rangeFrom = 1
+	rangeTo   = 12
+	// brokenBlock is the block the tests make fail or remove
+	brokenBlock = 5
+)
+
+// newRangeClient serves the hive chain, changed by edit, and returns a
+// client of it. The checks run with the fork schedule and fixtures of the
+// hive chain, and without seals.
+func newRangeClient(t *testing.T, edit func([]*mockgeth.Fixture) []*mockgeth.Fixture) *rpc.Client {
+	t.Helper()
+	var err error
+	if schedule, err = verify.LoadSchedule(filepath.Join(hiveDir, "genesis.json")); err != nil {
+		t.Fatal(err)
+	}
+	*dataDir, sealVerifier = hiveDir, nil
+
+	chain, err := mockgeth.LoadChain(hiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		chain = edit(chain)
+	}
+	httpServer := mockgeth.NewServer(chain...).Start()
+	t.Cleanup(httpServer.Close)
+	config := rpc.DefaultConfig()
+	config.Endpoint = httpServer.URL
+	client := rpc.NewClient(config)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// withoutBlock removes a block from the served chain
+func withoutBlock(number uint64) func([]*mockgeth.Fixture) []*mockgeth.Fixture {
+	return func(chain []*mockgeth.Fixture) []*mockgeth.Fixture {
+		var kept []*mockgeth.Fixture
+		for _, f := range chain {
+			if f.Number != number {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+}
+
+// wrongReceipts serves a block with a byte of its last raw receipt flipped
+func wrongReceipts(number uint64) func([]*mockgeth.Fixture) []*mockgeth.Fixture {
+	return func(chain []*mockgeth.Fixture) []*mockgeth.Fixture {
+		for i, f := range chain {
+			if f.Number != number || len(f.RawReceipts) == 0 {
+				continue
+			}
+			changed := *f
+			changed.RawReceipts = append([][]byte(nil), f.RawReceipts...)
+			last := append([]byte(nil), f.RawReceipts[len(f.RawReceipts)-1]...)
+			last[len(last)-1] ^= 0xff
+			changed.RawReceipts[len(changed.RawReceipts)-1] = last
+			chain[i] = &changed
+		}
+		return chain
+	}
+}
+
+func TestVerifyRange(t *testing.T) {
+	client := newRangeClient(t, nil)
+	results := VerifyRange(context.Background(), client, rangeFrom, rangeTo, 4, false)
+	if len(results) != rangeTo-rangeFrom+1 {
+		t.Fatalf("%d results, want %d", len(results), rangeTo-rangeFrom+1)
+	}
+	for i, r := range results {
+		if r.BlockNum != uint64(rangeFrom+i) {
+			t.Fatalf("result %d is of block %d, want %d", i, r.BlockNum, rangeFrom+i)
+		}
+		if !r.Passed() {
+			t.Fatalf("block %d: err %v, checks %v", r.BlockNum, r.Err, r.Checks)
+		}
+	}
+	if missing, err := CheckRangeChain(results); missing != 0 || err != nil {
+		t.Fatalf("chain: %d missing, error %v", missing, err)
+	}
+}
+
+func TestVerifyRangeFailFast(t *testing.T) {
+	client := newRangeClient(t, wrongReceipts(brokenBlock))
+	// a single worker starts the blocks in order, so the blocks after the
+	// failed one are the ones skipped
+	results := VerifyRange(context.Background(), client, rangeFrom, rangeTo, 1, true)
+	for _, r := range results {
+		switch {
+		case r.BlockNum < brokenBlock && !r.Passed():
+			t.Fatalf("block %d before the failed block: err %v, checks %v", r.BlockNum, r.Err, r.Checks)
+		case r.BlockNum == brokenBlock && (r.Passed() || r.Skipped || r.Err != nil):
+			t.Fatalf("block %d: passed %v, skipped %v, err %v, want failed checks", r.BlockNum, r.Passed(), r.Skipped, r.Err)
+		case r.BlockNum > brokenBlock && !r.Skipped:
+			t.Fatalf("block %d after the failed block is not skipped", r.BlockNum)
+		}
+	}
+	if missing, err := CheckRangeChain(results); missing != rangeTo-brokenBlock || err != nil {
+		t.Fatalf("chain: %d missing, error %v, want %d missing", missing, err, rangeTo-brokenBlock)
+	}
+
+	// the other workers finish the blocks they started
+	results = VerifyRange(context.Background(), client, rangeFrom, rangeTo, 4, true)
+	if last := results[len(results)-1]; !last.Skipped {
+		t.Fatalf("block %d: not skipped after the failed block", last.BlockNum)
+	}
+}
+
+func TestCheckRangeChain(t *testing.T) {
+	client := newRangeClient(t, withoutBlock(brokenBlock))
+	results := VerifyRange(context.Background(), client, rangeFrom, rangeTo, 4, false)
+	broken := results[brokenBlock-rangeFrom]
+	if !errors.Is(broken.Err, rpc.ErrBlockNotFound) || broken.Header != nil {
+		t.Fatalf("block %d: err %v, header %v, want block not found", broken.BlockNum, broken.Err, broken.Header)
+	}
+	if missing, err := CheckRangeChain(results); missing != 1 || err != nil {
+		t.Fatalf("chain: %d missing, error %v, want 1 missing", missing, err)
+	}
+
+	// a header after the missing block that is not the child of the one
+	// before it is reported at its index in the range
+	index := brokenBlock - rangeFrom + 3
+	results[index].Header = results[index+1].Header
+	_, err := CheckRangeChain(results)
+	var chainBreak *verify.ChainBreak
+	if !errors.As(err, &chainBreak) {
+		t.Fatalf("error %v, want a ChainBreak", err)
+	}
+	if chainBreak.Index != index {
+		t.Fatalf("break at index %d, want %d", chainBreak.Index, index)
+	}
+}
+
+func TestCountReason(t *testing.T) {
+	reasons := make(map[string]map[string]int)
+	for block := 3; block < 5; block++ {
+		countReason(reasons, "fetch", fmt.Errorf("debug_getRawReceipts: %w",
+			&rpc.Error{Code: 4444, Message: fmt.Sprintf("pruned history unavailable for block %d", block)}))
+		countReason(reasons, "fetch", fmt.Errorf("debug_getRawHeader: %w",
+			&rpc.Error{Code: -32000, Message: fmt.Sprintf("header for block %d not found", block)}))
+		countReason(reasons, "fetch", &rpc.Error{Code: -32601, Message: "the method debug_getRawBlock does not exist/is not available"})
+		countReason(reasons, "receipts", &verify.MismatchError{
+			Message: "receipts root does not match",
+			Diffs:   []verify.FieldDiff{{Field: fmt.Sprintf("receipt %d", block)}},
+		})
+		countReason(reasons, "receipts", fmt.Errorf("receipt %d does not decode", block))
+	}
+
+	want := map[string]map[string]int{
+		"fetch": {
+			rpc.ErrPruned.Error():         2,
+			rpc.ErrBlockNotFound.Error():  2,
+			rpc.ErrMethodNotFound.Error(): 2,
+		},
+		"receipts": {
+			"receipts root does not match": 2,
+			"receipt 3 does not decode":    1,
+			"receipt 4 does not decode":    1,
+		},
+	}
+	for check, counts := range want {
+		if len(reasons[check]) != len(counts) {
+			t.Fatalf("%s: reasons %v, want %v", check, reasons[check], counts)
+		}
+		for reason, count := range counts {
+			if reasons[check][reason] != count {
+				t.Fatalf("%s: reasons %v, want %v", check, reasons[check], counts)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BatchElem is one call of a batch. After BatchCall, Result holds the
+// decoded result of the call or Error is set.
+type BatchElem struct {
+	Method string
+	Params []interface{}
+	Result interface{} // pointer to decode the result into
+	Error  error
+}
+
+// BatchCall sends all calls in a single JSON-RPC batch. The calls get ids
+// 1 to len(batch) and responses are matched back by id, since a node may
+// answer them in any order. The returned error is for the batch as a whole,
+// such as a response with a duplicate id, errors of single calls are set on
+// their element.
+func (c *Client) BatchCall(ctx context.Context, batch []BatchElem) error {
+	requests := make([]RequestData, len(batch))
+	for i, elem := range batch {
+		requests[i] = NewRequest(elem.Method, elem.Params...)
+		requests[i].ID = i + 1
+	}
+
+	body, err := c.post(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	// a node that rejects the whole batch answers with a single response
+	var responses []rawResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		var single rawResponse
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			return single.Error
+		}
+		return fmt.Errorf("error decoding batch response: %w", err)
+	}
+
+	byID := make(map[string]*rawResponse, len(responses))
+	for i := range responses {
+		id := string(responses[i].ID)
+		if _, ok := byID[id]; ok && id != "null" {
+			return fmt.Errorf("malformed batch response: more than one response with id %s", id)
+		}
+		byID[id] = &responses[i]
+	}
+	for i := range batch {
+		elem := &batch[i]
+		res, ok := byID[fmt.Sprint(i+1)]
+		if !ok {
+			elem.Error = fmt.Errorf("%s: no response in batch", elem.Method)
+			continue
+		}
+		result, err := res.check(i + 1)
+		if err != nil {
+			elem.Error = fmt.Errorf("%s: %w", elem.Method, err)
+			continue
+		}
+		if err := json.Unmarshal(result, elem.Result); err != nil {
+			elem.Error = fmt.Errorf("%s: %w", elem.Method, err)
+		}
+	}
+	return nil
+}
+
+// BlockData is the raw and json data of a block fetched in one batch
+type BlockData struct {
+	Number      uint64
+	HeaderRlp   []byte
+	BlockRlp    []byte
+	RawReceipts [][]byte
+	Header      *types.Header // json header from eth_getBlockByNumber
+	Hash        common.Hash   // block hash reported with the json header
+	// BlockJSON is the eth_getBlockByNumber result, with full transaction
+	// objects from FetchFullBlockData and transaction hashes otherwise
+	BlockJSON json.RawMessage
+}
+
+// FetchBlockData fetch the rlp-encoded header and block, the raw receipts and
+// the json header of a block in a single batch. The batch is sent again with
+// the legacy method names when the node does not know the modern ones. The
+// first failed call is returned as the error.
+func (c *Client) FetchBlockData(ctx context.Context, blockNum uint64) (*BlockData, error) {
+	return c.fetchBlockData(ctx, blockNum, false)
+}
+
+// FetchFullBlockData is FetchBlockData with full transaction objects in the
+// json block
+func (c *Client) FetchFullBlockData(ctx context.Context, blockNum uint64) (*BlockData, error) {
+	return c.fetchBlockData(ctx, blockNum, true)
+}
+
+func (c *Client) fetchBlockData(ctx context.Context, blockNum uint64, fullTx bool) (*BlockData, error) {
+	var (
+		headerHex, blockHex *string
+		receiptsHex         []string
+		header              json.RawMessage
+		batch               []BatchElem
+	)
+	for {
+		headerName := c.methods.get(RawHeaderMethod)
+		blockName := c.methods.get(RawBlockMethod)
+		batch = []BatchElem{
+			{Method: headerName, Params: []interface{}{BlockParam(headerName, blockNum)}, Result: &headerHex},
+			{Method: blockName, Params: []interface{}{BlockParam(blockName, blockNum)}, Result: &blockHex},
+			{Method: "debug_getRawReceipts", Params: []interface{}{BlockParam("debug_getRawReceipts", blockNum)}, Result: &receiptsHex},
+			{Method: "eth_getBlockByNumber", Params: []interface{}{hexutil.EncodeUint64(blockNum), fullTx}, Result: &header},
+		}
+		if err := c.BatchCall(ctx, batch); err != nil {
+			return nil, err
+		}
+		// send the batch again with the legacy names the node knows
+		retry := c.methods.fallback(RawHeaderMethod, headerName, batch[0].Error)
+		retry = c.methods.fallback(RawBlockMethod, blockName, batch[1].Error) || retry
+		if !retry {
+			break
+		}
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+	}
+	c.methods.set(RawHeaderMethod, batch[0].Method)
+	c.methods.set(RawBlockMethod, batch[1].Method)
+
+	data := &BlockData{Number: blockNum, BlockJSON: header}
+	var err error
+	if data.HeaderRlp, err = decodeHexResult(batch[0].Method, headerHex); err != nil {
+		return nil, err
+	}
+	if data.BlockRlp, err = decodeHexResult(batch[1].Method, blockHex); err != nil {
+		return nil, err
+	}
+	data.RawReceipts = make([][]byte, len(receiptsHex))
+	for i, receiptHex := range receiptsHex {
+		data.RawReceipts[i], err = ResultToByteArray(receiptHex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: receipt %d: %w", batch[2].Method, i, err)
+		}
+	}
+	data.Header, data.Hash, err = decodeBlockHeader(batch[3].Method, header)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", blockNum, err)
+	}
+	return data, nil
+}
+
+// decodeHexResult decodes a single hex-encoded result, which is null when the
+// node does not have the block
+func decodeHexResult(method string, result *string) ([]byte, error) {
+	if result == nil {
+		return nil, fmt.Errorf("%s: %w", method, ErrBlockNotFound)
+	}
+	decoded, err := ResultToByteArray(*result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	return decoded, nil
+}
+
+// decodeBlockHeader decodes the header and hash of an eth_getBlockBy* result
+func decodeBlockHeader(method string, result json.RawMessage) (*types.Header, common.Hash, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return nil, common.Hash{}, ErrBlockNotFound
+	}
+	// types.Header has its own json decoding, so the hash is decoded apart
+	var header types.Header
+	if err := json.Unmarshal(result, &header); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: %w", method, err)
+	}
+	var hash struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(result, &hash); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("%s: %w", method, err)
+	}
+	return &header, hash.Hash, nil
+}
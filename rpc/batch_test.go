@@ -0,0 +1,101 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+const (
+	dataDir  = "../raw-data/data"
+	blockNum = 15209997
+)
+
+func TestBatchCall(t *testing.T) {
+	fixture, err := mockgeth.LoadFixture(dataDir, blockNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := mockgeth.NewServer(fixture)
+	httpServer := server.Start()
+	defer httpServer.Close()
+	config := rpc.DefaultConfig()
+	config.Endpoint = httpServer.URL
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	var (
+		number, chainID hexutil.Uint64
+		header          hexutil.Bytes
+		block           struct {
+			Hash common.Hash `json:"hash"`
+		}
+	)
+	batch := func() []rpc.BatchElem {
+		return []rpc.BatchElem{
+			{Method: "eth_blockNumber", Result: &number},
+			{Method: "debug_getRawHeader", Params: []interface{}{hexutil.EncodeUint64(blockNum)}, Result: &header},
+			{Method: "eth_chainId", Result: &chainID},
+			{Method: "eth_getBlockByNumber", Params: []interface{}{hexutil.EncodeUint64(blockNum), false}, Result: &block},
+		}
+	}
+
+	t.Run("reversed responses", func(t *testing.T) {
+		server.SetFaults(mockgeth.Fault{ReverseBatch: true})
+		elems := batch()
+		if err := client.BatchCall(context.Background(), elems); err != nil {
+			t.Fatal(err)
+		}
+		for _, elem := range elems {
+			if elem.Error != nil {
+				t.Fatalf("%s: %v", elem.Method, elem.Error)
+			}
+		}
+		if uint64(number) != blockNum {
+			t.Errorf("eth_blockNumber %d, want %d", number, blockNum)
+		}
+		if !bytes.Equal(header, fixture.HeaderRlp) {
+			t.Errorf("debug_getRawHeader is not the header of the fixture")
+		}
+		if chainID != 1 {
+			t.Errorf("eth_chainId %d, want 1", chainID)
+		}
+		if block.Hash != fixture.Hash {
+			t.Errorf("eth_getBlockByNumber hash %s, want %s", block.Hash, fixture.Hash)
+		}
+	})
+
+	t.Run("duplicate ids", func(t *testing.T) {
+		server.SetFaults(mockgeth.Fault{DuplicateID: true})
+		err := client.BatchCall(context.Background(), batch())
+		if err == nil || !strings.Contains(err.Error(), "malformed batch response") {
+			t.Fatalf("error %v, want a malformed batch response", err)
+		}
+	})
+
+	t.Run("failed call", func(t *testing.T) {
+		server.SetFaults()
+		var result json.RawMessage
+		elems := []rpc.BatchElem{
+			{Method: "eth_blockNumber", Result: &number},
+			{Method: "eth_unknown", Result: &result},
+		}
+		if err := client.BatchCall(context.Background(), elems); err != nil {
+			t.Fatal(err)
+		}
+		if elems[0].Error != nil {
+			t.Errorf("eth_blockNumber: %v", elems[0].Error)
+		}
+		if elems[1].Error == nil {
+			t.Errorf("eth_unknown: expected an error")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// block tags accepted by eth_getBlockByNumber
+var blockTags = map[string]bool{
+	"earliest":  true,
+	"latest":    true,
+	"pending":   true,
+	"safe":      true,
+	"finalized": true,
+}
+
+// BlockSelector selects a block by number, hash or tag. Exactly one of the
+// fields is set.
+type BlockSelector struct {
+	Number *uint64
+	Hash   *common.Hash
+	Tag    string
+}
+
+// BlockByNumber selects the block with the given number
+func BlockByNumber(blockNum uint64) BlockSelector {
+	return BlockSelector{Number: &blockNum}
+}
+
+// ParseBlockSelector parses a decimal or hex block number, a 32 byte block
+// hash or a block tag such as "latest" or "finalized"
+func ParseBlockSelector(s string) (BlockSelector, error) {
+	s = strings.TrimSpace(s)
+	if blockTags[s] {
+		return BlockSelector{Tag: s}, nil
+	}
+	if strings.HasPrefix(s, "0x") && len(s) == 2+2*common.HashLength {
+		hash := common.HexToHash(s)
+		return BlockSelector{Hash: &hash}, nil
+	}
+	blockNum, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return BlockSelector{}, fmt.Errorf("invalid block %q: not a number, hash or tag", s)
+	}
+	return BlockByNumber(blockNum), nil
+}
+
+// String returns the selector as a block parameter for eth_getBlockByNumber,
+// or the block hash
+func (b BlockSelector) String() string {
+	switch {
+	case b.Number != nil:
+		return fmt.Sprintf("0x%x", *b.Number)
+	case b.Hash != nil:
+		return b.Hash.Hex()
+	default:
+		return b.Tag
+	}
+}
+
+// MaxBlockRange is the largest number of blocks in a range. A range is
+// verified or archived block by block, with state held for each of them.
+const MaxBlockRange = 100000
+
+// ErrRangeTooLarge is returned for a range of more than MaxBlockRange blocks
+var ErrRangeTooLarge = errors.New("block range too large")
+
+// ParseBlockRange parses a single block number or an inclusive range of
+// numbers written as "N..M", of at most MaxBlockRange blocks
+func ParseBlockRange(s string) (uint64, uint64, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.SplitN(s, "..", 2)
+	from, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid block range %q: expected N or N..M", s)
+	}
+	if len(parts) == 1 {
+		return from, from, nil
+	}
+	to, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid block range %q: expected N or N..M", s)
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("invalid block range %q: end is before start", s)
+	}
+	if to-from >= MaxBlockRange {
+		return 0, 0, fmt.Errorf("%w: %q has more than %d blocks", ErrRangeTooLarge, s, MaxBlockRange)
+	}
+	return from, to, nil
+}
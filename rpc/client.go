@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Config holds the settings of a Client
+type Config struct {
+	// Endpoint is the http url of the node
+	Endpoint string
+	// Headers are added to every request
+	Headers http.Header
+	// JWTSecret is the secret shared with the node's authenticated rpc port.
+	// Requests carry a fresh bearer token when it is set.
+	JWTSecret []byte
+
+	// CallTimeout bounds each attempt of a call, no bound when zero
+	CallTimeout time.Duration
+	// Retries is the number of times a call is sent again after a transient
+	// error, such as a refused connection or a timeout
+	Retries int
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further retry up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// MaxInFlight caps the number of requests sent at once, no cap when zero
+	MaxInFlight int
+}
+
+// DefaultConfig returns the config of a local Geth node without auth
+func DefaultConfig() Config {
+	return Config{
+		Endpoint:     DefaultEndpoint,
+		Headers:      make(http.Header),
+		CallTimeout:  30 * time.Second,
+		Retries:      3,
+		RetryBackoff: 250 * time.Millisecond,
+		MaxBackoff:   5 * time.Second,
+		MaxInFlight:  16,
+	}
+}
+
+// Client makes JSON-RPC calls to a single node. It is safe for concurrent
+// use.
+type Client struct {
+	config    Config
+	transport Transport
+	inFlight  chan struct{} // semaphore, nil when there is no cap
+	methods   methodNames   // names of the renamed raw methods
+}
+
+// NewClient creates a client with the given config, over the transport that
+// matches its endpoint
+func NewClient(config Config) *Client {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultEndpoint
+	}
+	return NewClientWithTransport(config, NewTransport(config))
+}
+
+// NewClientWithTransport creates a client that makes its calls over the
+// given transport
+func NewClientWithTransport(config Config, transport Transport) *Client {
+	c := &Client{config: config, transport: transport}
+	if config.MaxInFlight > 0 {
+		c.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+	return c
+}
+
+// Endpoint returns the url or socket path the client sends to
+func (c *Client) Endpoint() string {
+	return c.config.Endpoint
+}
+
+// Close closes the connections of the client
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// ExecuteRequest make a rpc call to the client with the given request data,
+// and return the encoded response
+func (c *Client) ExecuteRequest(ctx context.Context, data RequestData) ([]byte, error) {
+	return c.post(ctx, data)
+}
+
+// post sends a single request or a batch of requests to the client, and
+// sends idempotent requests again after transient errors, with exponential
+// backoff
+func (c *Client) post(ctx context.Context, payload interface{}) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := c.config.Retries
+	if !idempotent(payload) {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, backoff(c.config.RetryBackoff, c.config.MaxBackoff, attempt)); err != nil {
+				return nil, err
+			}
+		}
+		response, err := c.send(ctx, payloadBytes)
+		if err == nil {
+			return response, nil
+		}
+		if attempt < retries && transient(ctx, err) {
+			continue
+		}
+		if attempt > 0 {
+			return nil, fmt.Errorf("after %d attempts: %w", attempt+1, err)
+		}
+		return nil, err
+	}
+}
+
+// send makes a single attempt within the call timeout
+func (c *Client) send(ctx context.Context, payload []byte) ([]byte, error) {
+	if c.inFlight != nil {
+		select {
+		case c.inFlight <- struct{}{}:
+			defer func() { <-c.inFlight }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.config.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.CallTimeout)
+		defer cancel()
+	}
+	return c.transport.RoundTrip(ctx, payload)
+}
+
+// call makes a call and decodes its result into result. Errors carry the
+// method name and wrap the *Error or *HTTPError from the node.
+func (c *Client) call(ctx context.Context, data RequestData, result interface{}) error {
+	body, err := c.ExecuteRequest(ctx, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", data.Method, err)
+	}
+
+	resData, err := DecodeResponse(body, data.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", data.Method, err)
+	}
+	if err := json.Unmarshal(resData, result); err != nil {
+		return fmt.Errorf("%s: %w", data.Method, err)
+	}
+	return nil
+}
+
+// callBytes makes a call whose result is a single hex-encoded string
+func (c *Client) callBytes(ctx context.Context, data RequestData) ([]byte, error) {
+	var result *string
+	if err := c.call(ctx, data, &result); err != nil {
+		return nil, err
+	}
+	return decodeHexResult(data.Method, result)
+}
+
+// GetRawReceipts fetch the binary-encoded receipts of a block with
+// debug_getRawReceipts
+func (c *Client) GetRawReceipts(ctx context.Context, blockNum uint64) ([][]byte, error) {
+	data := NewRequest("debug_getRawReceipts", BlockParam("debug_getRawReceipts", blockNum))
+	var result []string
+	if err := c.call(ctx, data, &result); err != nil {
+		return nil, err
+	}
+
+	encodedReceipts := make([][]byte, len(result))
+	for i, receiptHex := range result {
+		var err error
+		encodedReceipts[i], err = ResultToByteArray(receiptHex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: receipt %d: %w", data.Method, i, err)
+		}
+	}
+	return encodedReceipts, nil
+}
+
+// HeaderByBlock fetch the json header of the selected block with
+// eth_getBlockByNumber or eth_getBlockByHash, and return it along with the
+// hash reported by the node
+func (c *Client) HeaderByBlock(ctx context.Context, block BlockSelector) (*types.Header, common.Hash, error) {
+	var data RequestData
+	if block.Hash != nil {
+		data = NewRequest("eth_getBlockByHash", *block.Hash, false)
+	} else {
+		data = NewRequest("eth_getBlockByNumber", block.String(), false)
+	}
+
+	var result json.RawMessage
+	if err := c.call(ctx, data, &result); err != nil {
+		return nil, common.Hash{}, err
+	}
+	header, hash, err := decodeBlockHeader(data.Method, result)
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("block %s: %w", block, err)
+	}
+	return header, hash, nil
+}
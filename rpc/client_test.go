@@ -0,0 +1,149 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// countingServer serves the fixture block and counts the http requests it
+// gets, and the most it handled at once
+type countingServer struct {
+	*mockgeth.Server
+	mu          sync.Mutex
+	requests    int
+	inFlight    int
+	maxInFlight int
+}
+
+func newCountingServer(t *testing.T) (*countingServer, string) {
+	t.Helper()
+	fixture, err := mockgeth.LoadFixture(dataDir, blockNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &countingServer{Server: mockgeth.NewServer(fixture)}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		if s.inFlight++; s.inFlight > s.maxInFlight {
+			s.maxInFlight = s.inFlight
+		}
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+		s.Server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return s, httpServer.URL
+}
+
+// reset clears the counts and sets the faults of the server
+func (s *countingServer) reset(faults ...mockgeth.Fault) {
+	s.Wait()
+	s.SetFaults(faults...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests, s.maxInFlight = 0, 0
+}
+
+func (s *countingServer) counts() (requests, maxInFlight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.maxInFlight
+}
+
+func TestMaxInFlight(t *testing.T) {
+	const (
+		maxInFlight = 3
+		calls       = 4 * maxInFlight
+	)
+	server, endpoint := newCountingServer(t)
+	server.reset(mockgeth.Fault{Delay: 50 * time.Millisecond})
+	config := testConfig(endpoint)
+	config.MaxInFlight = maxInFlight
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ExecuteRequest(context.Background(), rpc.NewRequest("eth_blockNumber")); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if requests, max := server.counts(); requests != calls || max != maxInFlight {
+		t.Fatalf("%d requests, at most %d at once, want %d requests and %d at once", requests, max, calls, maxInFlight)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	server, endpoint := newCountingServer(t)
+	config := testConfig(endpoint)
+	config.Retries = 3
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		requests int // http requests the call makes
+	}{
+		{
+			name: "idempotent call",
+			call: func() error {
+				_, err := client.ExecuteRequest(context.Background(), rpc.NewRequest("eth_blockNumber"))
+				return err
+			},
+			requests: config.Retries + 1,
+		},
+		{
+			name: "eth_sendRawTransaction",
+			call: func() error {
+				_, err := client.ExecuteRequest(context.Background(), rpc.NewRequest("eth_sendRawTransaction", "0x00"))
+				return err
+			},
+			requests: 1,
+		},
+		{
+			name: "batch with an eth_sendRawTransaction",
+			call: func() error {
+				var number, hash interface{}
+				return client.BatchCall(context.Background(), []rpc.BatchElem{
+					{Method: "eth_blockNumber", Result: &number},
+					{Method: "eth_sendRawTransaction", Params: []interface{}{"0x00"}, Result: &hash},
+				})
+			},
+			requests: 1,
+		},
+	}
+	for _, test := range tests {
+		// the node drops the connection of every request, an error worth
+		// retrying for a call that does not change the node
+		server.reset(mockgeth.Fault{Drop: true})
+		if err := test.call(); err == nil {
+			t.Fatalf("%s: expected an error from the dropped connections", test.name)
+		}
+		if requests, _ := server.counts(); requests != test.requests {
+			t.Fatalf("%s: %d requests, want %d", test.name, requests, test.requests)
+		}
+	}
+}
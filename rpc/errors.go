@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JSON-RPC error codes used to classify errors from the node
+const (
+	codeMethodNotFound = -32601
+	codePrunedHistory  = 4444 // returned by nodes that expired old history
+)
+
+// Errors that callers can match with errors.Is. An *Error from the node
+// matches the one that fits its code and message.
+var (
+	ErrMethodNotFound = errors.New("method not found")
+	ErrBlockNotFound  = errors.New("block not found")
+	ErrPruned         = errors.New("data pruned")
+	ErrTxNotFound     = errors.New("transaction not found")
+)
+
+// Error is the error object of a JSON-RPC response
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Is classifies the error by its code and message, as nodes only agree on
+// the code for unknown methods
+func (e *Error) Is(target error) bool {
+	message := strings.ToLower(e.Message)
+	switch target {
+	case ErrMethodNotFound:
+		return e.Code == codeMethodNotFound
+	case ErrBlockNotFound:
+		return strings.Contains(message, "not found") &&
+			(strings.Contains(message, "block") || strings.Contains(message, "header"))
+	case ErrPruned:
+		return e.Code == codePrunedHistory || strings.Contains(message, "pruned")
+	}
+	return false
+}
+
+// HTTPError is returned when the node answers with a non-2xx status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string // start of the response body
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("http error: %s", e.Status)
+	}
+	return fmt.Sprintf("http error: %s: %s", e.Status, e.Body)
+}
@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables read by RegisterFlags. They set the defaults of the
+// matching flags, so a flag given on the command line takes precedence.
+const (
+	EnvEndpoint  = "ETH_RPC_URL"
+	EnvHeaders   = "ETH_RPC_HEADERS" // "Name: value" pairs separated by ';'
+	EnvJWTSecret = "ETH_RPC_JWT_SECRET"
+	EnvBlock     = "ETH_RPC_BLOCK" // block number, hash or tag
+)
+
+// headerList collects repeated -header flags
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, "; ")
+}
+
+func (h *headerList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+// Flags holds the client flags registered on a flag set
+type Flags struct {
+	endpoint  *string
+	jwtSecret *string
+	block     *string
+	headers   headerList
+
+	callTimeout *time.Duration
+	timeout     *time.Duration
+	retries     *int
+	maxInFlight *int
+}
+
+// RegisterFlags registers -rpc, -header, -jwtsecret and -block on fs, with
+// defaultBlock used when neither -block nor its environment variable is set,
+// along with the timeout and retry flags. Call Config and Block once fs has
+// been parsed.
+func RegisterFlags(fs *flag.FlagSet, defaultBlock string) *Flags {
+	f := new(Flags)
+	f.block = fs.String("block", envOr(EnvBlock, defaultBlock),
+		"block number, hash or tag such as latest or finalized (env "+EnvBlock+")")
+	f.endpoint = fs.String("rpc", envOr(EnvEndpoint, DefaultEndpoint),
+		"http or ws url, or ipc socket path of the node (env "+EnvEndpoint+")")
+	f.jwtSecret = fs.String("jwtsecret", os.Getenv(EnvJWTSecret),
+		"file with the hex jwt secret of the node's authenticated port (env "+EnvJWTSecret+")")
+	fs.Var(&f.headers, "header",
+		"extra http header as \"Name: value\", can be repeated (env "+EnvHeaders+", separated by ';')")
+
+	defaults := DefaultConfig()
+	f.callTimeout = fs.Duration("calltimeout", defaults.CallTimeout, "timeout of each attempt of a call, 0 for none")
+	f.timeout = fs.Duration("timeout", 0, "timeout of the whole run, 0 for none")
+	f.retries = fs.Int("retries", defaults.Retries, "retries of a call after a transient error")
+	f.maxInFlight = fs.Int("maxinflight", defaults.MaxInFlight, "maximum number of requests sent at once, 0 for no limit")
+	return f
+}
+
+// Config returns the client config set by the flags and environment
+func (f *Flags) Config() (Config, error) {
+	config := DefaultConfig()
+	config.Endpoint = *f.endpoint
+	config.CallTimeout = *f.callTimeout
+	config.Retries = *f.retries
+	config.MaxInFlight = *f.maxInFlight
+
+	headers := f.headers
+	if len(headers) == 0 && os.Getenv(EnvHeaders) != "" {
+		headers = strings.Split(os.Getenv(EnvHeaders), ";")
+	}
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+		if err != nil {
+			return Config{}, err
+		}
+		config.Headers.Add(name, value)
+	}
+
+	if *f.jwtSecret != "" {
+		secret, err := ReadJWTSecret(*f.jwtSecret)
+		if err != nil {
+			return Config{}, err
+		}
+		config.JWTSecret = secret
+	}
+	return config, nil
+}
+
+// Context returns a context that is done after the -timeout of the whole
+// run, or a context without deadline when it is 0
+func (f *Flags) Context() (context.Context, context.CancelFunc) {
+	if *f.timeout > 0 {
+		return context.WithTimeout(context.Background(), *f.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Block returns the block selected by the flags and environment
+func (f *Flags) Block() (BlockSelector, error) {
+	return ParseBlockSelector(*f.block)
+}
+
+// Range returns the block range selected by the flags and environment, for
+// commands that take N..M as their -block
+func (f *Flags) Range() (uint64, uint64, error) {
+	return ParseBlockRange(*f.block)
+}
+
+// parseHeader splits a "Name: value" header
+func parseHeader(header string) (string, string, error) {
+	parts := strings.SplitN(header, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+	}
+	return http.CanonicalHeaderKey(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1]), nil
+}
+
+// envOr returns the value of the environment variable, or def if it is unset
+func envOr(name, def string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// jwtHeader is the base64url-encoded {"alg":"HS256","typ":"JWT"}
+const jwtHeader = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9"
+
+// NewJWTToken creates an HS256 token with the current time as its issued-at
+// claim, as required by the authenticated rpc port of execution clients
+func NewJWTToken(secret []byte) (string, error) {
+	if len(secret) != 32 {
+		return "", fmt.Errorf("jwt secret must be 32 bytes, got %d", len(secret))
+	}
+	claims := fmt.Sprintf(`{"iat":%d}`, time.Now().Unix())
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ReadJWTSecret reads a hex-encoded 32 byte secret from a file, in the same
+// format as Geth's --authrpc.jwtsecret
+func ReadJWTSecret(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading jwt secret: %w", err)
+	}
+	secret := common.FromHex(strings.TrimSpace(string(data)))
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("invalid jwt secret in %s: must be 32 hex-encoded bytes", path)
+	}
+	return secret, nil
+}
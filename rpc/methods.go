@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// RawMethod is a raw debug method that newer Geth versions renamed. Modern
+// is tried first and Legacy is used on nodes that do not know it.
+type RawMethod struct {
+	Modern string
+	Legacy string
+}
+
+var (
+	RawHeaderMethod = RawMethod{Modern: "debug_getRawHeader", Legacy: "debug_getHeaderRlp"}
+	RawBlockMethod  = RawMethod{Modern: "debug_getRawBlock", Legacy: "debug_getBlockRlp"}
+)
+
+// legacyMethods take the block as a plain json number. All other methods
+// take a block number or hash, where numbers are hex strings.
+var legacyMethods = map[string]bool{
+	RawHeaderMethod.Legacy: true,
+	RawBlockMethod.Legacy:  true,
+}
+
+// BlockParam returns the block parameter in the form method expects
+func BlockParam(method string, blockNum uint64) interface{} {
+	if legacyMethods[method] {
+		return blockNum
+	}
+	return hexutil.EncodeUint64(blockNum)
+}
+
+// methodNames remembers which name of each raw method the node answers to
+type methodNames struct {
+	mu    sync.Mutex
+	names map[RawMethod]string
+}
+
+// get returns the name known to work, or the modern name before discovery
+func (m *methodNames) get(method RawMethod) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name, ok := m.names[method]; ok {
+		return name
+	}
+	return method.Modern
+}
+
+func (m *methodNames) set(method RawMethod, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.names == nil {
+		m.names = make(map[RawMethod]string)
+	}
+	m.names[method] = name
+}
+
+// fallback switches to the legacy name after the modern one was not found,
+// and reports whether the call should be made again
+func (m *methodNames) fallback(method RawMethod, name string, err error) bool {
+	if name != method.Modern || !errors.Is(err, ErrMethodNotFound) {
+		return false
+	}
+	m.set(method, method.Legacy)
+	return true
+}
+
+// MethodName returns the name the client uses for a raw method: the one the
+// node answered to, or the modern name before the first call
+func (c *Client) MethodName(method RawMethod) string {
+	return c.methods.get(method)
+}
+
+// callRaw calls a raw method for a block, with the name the node supports
+// and its block parameter
+func (c *Client) callRaw(ctx context.Context, method RawMethod, blockNum uint64) ([]byte, error) {
+	for {
+		name := c.methods.get(method)
+		result, err := c.callBytes(ctx, NewRequest(name, BlockParam(name, blockNum)))
+		if c.methods.fallback(method, name, err) {
+			continue
+		}
+		if err == nil {
+			c.methods.set(method, name)
+		}
+		return result, err
+	}
+}
+
+// GetHeaderRlp fetch the rlp-encoded header of a block with
+// debug_getRawHeader, or debug_getHeaderRlp on older nodes
+func (c *Client) GetHeaderRlp(ctx context.Context, blockNum uint64) ([]byte, error) {
+	return c.callRaw(ctx, RawHeaderMethod, blockNum)
+}
+
+// GetBlockRlp fetch the rlp-encoded block with debug_getRawBlock, or
+// debug_getBlockRlp on older nodes
+func (c *Client) GetBlockRlp(ctx context.Context, blockNum uint64) ([]byte, error) {
+	return c.callRaw(ctx, RawBlockMethod, blockNum)
+}
+
+// GetRawTransaction fetch the binary-encoded transaction with
+// debug_getRawTransaction
+func (c *Client) GetRawTransaction(ctx context.Context, txHash common.Hash) ([]byte, error) {
+	var result *hexutil.Bytes
+	if err := c.call(ctx, NewRequest("debug_getRawTransaction", txHash), &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("debug_getRawTransaction %s: %w", txHash, ErrTxNotFound)
+	}
+	return *result, nil
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// methods that change state on the node and are never retried, since a call
+// that timed out may still have been executed
+var nonIdempotent = map[string]bool{
+	"eth_sendRawTransaction":   true,
+	"eth_sendTransaction":      true,
+	"personal_sendTransaction": true,
+}
+
+// idempotent reports whether every request of a single request or batch can
+// be sent again
+func idempotent(payload interface{}) bool {
+	switch p := payload.(type) {
+	case RequestData:
+		return !nonIdempotent[p.Method]
+	case []RequestData:
+		for _, data := range p {
+			if nonIdempotent[data.Method] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// transientStatus are the http statuses of a node that is overloaded or
+// restarting behind a proxy
+var transientStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// transient reports whether a transport error may go away on its own, such
+// as a refused or reset connection, a call that timed out or an overloaded
+// node. ctx is the context of the whole call, which is never retried once
+// done.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return transientStatus[httpErr.StatusCode]
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns the wait before retry number attempt, starting at 1: the
+// base delay doubled for every attempt, capped at max, with the upper half
+// randomized so that workers do not retry in lockstep
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
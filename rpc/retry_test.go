@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	const (
+		base    = 100 * time.Millisecond
+		max     = time.Second
+		samples = 100
+	)
+	for attempt, full := range []time.Duration{base, 2 * base, 4 * base, 8 * base, max, max} {
+		attempt++
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < samples; i++ {
+			d := backoff(base, max, attempt)
+			if d < full/2 || d > full {
+				t.Fatalf("retry %d: backoff %s, want between %s and %s", attempt, d, full/2, full)
+			}
+			seen[d] = true
+		}
+		// the jitter spreads the retries of workers that failed together
+		if len(seen) < samples/2 {
+			t.Fatalf("retry %d: %d distinct backoffs of %d", attempt, len(seen), samples)
+		}
+	}
+	if d := backoff(0, max, 1); d != 0 {
+		t.Fatalf("backoff %s without a base delay, want 0", d)
+	}
+}
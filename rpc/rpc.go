@@ -0,0 +1,98 @@
+// Package rpc makes JSON-RPC calls to an Ethereum client, mainly to fetch
+// rlp-encoded data from Geth's debug namespace.
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DefaultEndpoint is the http endpoint of a local Geth node
+const DefaultEndpoint = "http://127.0.0.1:8545/"
+
+// RequestData struct to hold parameters for rpc call
+type RequestData struct {
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      int           `json:"id"`
+	Jsonrpc string        `json:"jsonrpc"`
+}
+
+// rawResponse is a response whose result is decoded later. The id is kept
+// raw as nodes answer with a null id when they cannot parse the request.
+type rawResponse struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// NewRequest creates the request data for a call to method with params
+func NewRequest(method string, params ...interface{}) RequestData {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return RequestData{
+		Method:  method,
+		Params:  params,
+		ID:      1,
+		Jsonrpc: "2.0",
+	}
+}
+
+// DecodeResponse check the jsonrpc version and the id of an encoded response
+// to the request with the given id, and return its raw result. An error
+// object in the response is returned as an *Error.
+func DecodeResponse(body []byte, id int) (json.RawMessage, error) {
+	var resData rawResponse
+	if err := json.Unmarshal(body, &resData); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return resData.check(id)
+}
+
+// checkStatus returns an *HTTPError for a non-2xx response
+func checkStatus(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+	return &HTTPError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+// check the response against the request id and return its raw result
+func (r *rawResponse) check(id int) (json.RawMessage, error) {
+	if r.Jsonrpc != "2.0" {
+		return nil, fmt.Errorf("unexpected jsonrpc version %q", r.Jsonrpc)
+	}
+	// errors about the request itself can come back with a null id
+	if r.Error != nil && (string(r.ID) == "null" || len(r.ID) == 0) {
+		return nil, r.Error
+	}
+	if string(r.ID) != strconv.Itoa(id) {
+		return nil, fmt.Errorf("response id %s does not match request id %d", r.ID, id)
+	}
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	if len(r.Result) == 0 {
+		return nil, fmt.Errorf("response has neither a result nor an error")
+	}
+	return r.Result, nil
+}
+
+// ResultToByteArray parse hex-encoded string into a byte array
+func ResultToByteArray(resString string) ([]byte, error) {
+	return hexutil.Decode(resString)
+}
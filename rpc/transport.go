@@ -0,0 +1,272 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport sends an encoded JSON-RPC request or batch to a node and returns
+// the encoded response. Implementations are safe for concurrent use.
+type Transport interface {
+	RoundTrip(ctx context.Context, payload []byte) ([]byte, error)
+	Close() error
+}
+
+// NewTransport returns the transport for the endpoint of the config: http
+// for http:// and https:// urls, WebSocket for ws:// and wss:// urls, and
+// IPC for anything else, which is taken as the path of a Unix socket such as
+// geth.ipc
+func NewTransport(config Config) Transport {
+	endpoint := config.Endpoint
+	switch {
+	case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+		return &httpTransport{config: config, client: new(http.Client)}
+	case strings.HasPrefix(endpoint, "ws://"), strings.HasPrefix(endpoint, "wss://"):
+		return newStreamTransport(func(ctx context.Context) (streamConn, error) {
+			return dialWebSocket(ctx, config)
+		})
+	case strings.Contains(endpoint, "://"):
+		return errTransport{fmt.Errorf("unsupported endpoint %q", endpoint)}
+	}
+	return newStreamTransport(func(ctx context.Context) (streamConn, error) {
+		return dialIPC(ctx, endpoint)
+	})
+}
+
+// authHeaders returns the configured headers, with a fresh bearer token when
+// the config has a jwt secret
+func authHeaders(config Config) (http.Header, error) {
+	headers := make(http.Header)
+	for name, values := range config.Headers {
+		for _, value := range values {
+			headers.Add(name, value)
+		}
+	}
+	if config.JWTSecret != nil {
+		token, err := NewJWTToken(config.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	return headers, nil
+}
+
+// httpTransport posts each request
+type httpTransport struct {
+	config Config
+	client *http.Client
+}
+
+func (t *httpTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if req.Header, err = authHeaders(t.config); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (t *httpTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// streamConn is a connection that carries one request and its response at a
+// time
+type streamConn interface {
+	roundTrip(payload []byte) ([]byte, error)
+	setDeadline(t time.Time) error
+	close() error
+}
+
+// streamTransport keeps a pool of idle connections, so concurrent calls each
+// get their own connection and later calls reuse them
+type streamTransport struct {
+	dial func(ctx context.Context) (streamConn, error)
+
+	mu     sync.Mutex
+	idle   []streamConn
+	closed bool
+}
+
+func newStreamTransport(dial func(ctx context.Context) (streamConn, error)) *streamTransport {
+	return &streamTransport{dial: dial}
+}
+
+func (t *streamTransport) RoundTrip(ctx context.Context, payload []byte) ([]byte, error) {
+	conn, err := t.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// unblock the connection when ctx is done before the response is read.
+	// The deadline is only set then, so a failed read always has ctx.Err().
+	// The watcher is joined before the connection is reused, so it cannot
+	// set a deadline on the next call.
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			conn.setDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	response, err := conn.roundTrip(payload)
+	close(done)
+	<-exited
+
+	if err != nil || ctx.Err() != nil {
+		// the connection may hold half a message, or have a deadline in the
+		// past, so it is not reused
+		conn.close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if err := conn.setDeadline(time.Time{}); err != nil {
+		conn.close()
+		return response, nil
+	}
+	t.put(conn)
+	return response, nil
+}
+
+func (t *streamTransport) get(ctx context.Context) (streamConn, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("transport is closed")
+	}
+	if n := len(t.idle); n > 0 {
+		conn := t.idle[n-1]
+		t.idle = t.idle[:n-1]
+		t.mu.Unlock()
+		return conn, nil
+	}
+	t.mu.Unlock()
+	return t.dial(ctx)
+}
+
+func (t *streamTransport) put(conn streamConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		conn.close()
+		return
+	}
+	t.idle = append(t.idle, conn)
+}
+
+func (t *streamTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for _, conn := range t.idle {
+		conn.close()
+	}
+	t.idle = nil
+	return nil
+}
+
+// ipcConn is a Unix socket connection to the node. Requests and responses
+// are json values written one after the other, without framing.
+type ipcConn struct {
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+func dialIPC(ctx context.Context, path string) (streamConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &ipcConn{conn: conn, dec: json.NewDecoder(conn)}, nil
+}
+
+func (c *ipcConn) roundTrip(payload []byte) ([]byte, error) {
+	if _, err := c.conn.Write(payload); err != nil {
+		return nil, err
+	}
+	var response json.RawMessage
+	if err := c.dec.Decode(&response); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *ipcConn) setDeadline(t time.Time) error { return c.conn.SetDeadline(t) }
+func (c *ipcConn) close() error                  { return c.conn.Close() }
+
+// wsConn is a WebSocket connection to the node, with one request or batch
+// per text message
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func dialWebSocket(ctx context.Context, config Config) (streamConn, error) {
+	headers, err := authHeaders(config)
+	if err != nil {
+		return nil, err
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, config.Endpoint, headers)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+		return nil, err
+	}
+	return &wsConn{conn: conn}, nil
+}
+
+func (c *wsConn) roundTrip(payload []byte) ([]byte, error) {
+	if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+		return nil, err
+	}
+	_, response, err := c.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsUnexpectedCloseError(err) || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *wsConn) setDeadline(t time.Time) error { return c.conn.UnderlyingConn().SetDeadline(t) }
+func (c *wsConn) close() error                  { return c.conn.Close() }
+
+// errTransport fails every call, for endpoints no transport supports
+type errTransport struct{ err error }
+
+func (t errTransport) RoundTrip(context.Context, []byte) ([]byte, error) { return nil, t.err }
+func (t errTransport) Close() error                                      { return nil }
@@ -0,0 +1,199 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// transports starts the server on each transport and returns its endpoint
+var transports = []struct {
+	name  string
+	start func(t *testing.T, server *mockgeth.Server) string
+}{
+	{"http", func(t *testing.T, server *mockgeth.Server) string {
+		httpServer := server.Start()
+		t.Cleanup(httpServer.Close)
+		return httpServer.URL
+	}},
+	{"ipc", func(t *testing.T, server *mockgeth.Server) string {
+		path := filepath.Join(t.TempDir(), "geth.ipc")
+		l, err := server.StartIPC(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		return path
+	}},
+	{"ws", func(t *testing.T, server *mockgeth.Server) string {
+		httpServer := server.Start()
+		t.Cleanup(httpServer.Close)
+		return mockgeth.WebSocketURL(httpServer)
+	}},
+}
+
+// blockNumberRequest is an encoded eth_blockNumber call with id 1
+func blockNumberRequest(t *testing.T) []byte {
+	t.Helper()
+	payload, err := json.Marshal(rpc.NewRequest("eth_blockNumber"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+// checkBlockNumber checks that body is the response to blockNumberRequest
+func checkBlockNumber(t *testing.T, body []byte) {
+	t.Helper()
+	result, err := rpc.DecodeResponse(body, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%q", hexutil.EncodeUint64(blockNum)); string(result) != want {
+		t.Fatalf("eth_blockNumber result %s, want %s", result, want)
+	}
+}
+
+// testConfig returns the config of a client for the endpoint that retries
+// quickly
+func testConfig(endpoint string) rpc.Config {
+	config := rpc.DefaultConfig()
+	config.Endpoint = endpoint
+	config.RetryBackoff = 10 * time.Millisecond
+	return config
+}
+
+func TestTransports(t *testing.T) {
+	fixture, err := mockgeth.LoadFixture(dataDir, blockNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range transports {
+		tr := tr
+		t.Run(tr.name, func(t *testing.T) {
+			t.Parallel()
+			server := mockgeth.NewServer(fixture)
+			endpoint := tr.start(t, server)
+			// every subtest starts without faults, after the messages of the
+			// one before it are answered
+			reset := func(faults ...mockgeth.Fault) {
+				server.Wait()
+				server.SetFaults(faults...)
+			}
+
+			t.Run("round trip", func(t *testing.T) {
+				reset()
+				transport := rpc.NewTransport(testConfig(endpoint))
+				defer transport.Close()
+				for i := 0; i < 3; i++ {
+					body, err := transport.RoundTrip(context.Background(), blockNumberRequest(t))
+					if err != nil {
+						t.Fatal(err)
+					}
+					checkBlockNumber(t, body)
+				}
+			})
+
+			t.Run("batch", func(t *testing.T) {
+				reset()
+				client := rpc.NewClient(testConfig(endpoint))
+				defer client.Close()
+				var number hexutil.Uint64
+				var header, unknown hexutil.Bytes
+				batch := []rpc.BatchElem{
+					{Method: "eth_blockNumber", Result: &number},
+					{Method: "debug_getRawHeader", Params: []interface{}{hexutil.EncodeUint64(blockNum)}, Result: &header},
+					{Method: "debug_getRawHeader", Params: []interface{}{hexutil.EncodeUint64(blockNum + 1)}, Result: &unknown},
+				}
+				if err := client.BatchCall(context.Background(), batch); err != nil {
+					t.Fatal(err)
+				}
+				if batch[0].Error != nil || uint64(number) != blockNum {
+					t.Errorf("eth_blockNumber: %d, %v, want %d", number, batch[0].Error, blockNum)
+				}
+				if batch[1].Error != nil || !bytes.Equal(header, fixture.HeaderRlp) {
+					t.Errorf("debug_getRawHeader: %v, want the header of the fixture", batch[1].Error)
+				}
+				if batch[2].Error == nil {
+					t.Errorf("debug_getRawHeader of an unknown block: expected an error")
+				}
+			})
+
+			t.Run("dropped connection", func(t *testing.T) {
+				reset(mockgeth.Fault{Drop: true, Times: 1})
+				transport := rpc.NewTransport(testConfig(endpoint))
+				defer transport.Close()
+				if _, err := transport.RoundTrip(context.Background(), blockNumberRequest(t)); err == nil {
+					t.Fatal("expected an error from a dropped connection")
+				}
+				// the dropped connection is not reused
+				body, err := transport.RoundTrip(context.Background(), blockNumberRequest(t))
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkBlockNumber(t, body)
+
+				reset(mockgeth.Fault{Drop: true, Times: 2})
+				client := rpc.NewClient(testConfig(endpoint))
+				defer client.Close()
+				body, err = client.ExecuteRequest(context.Background(), rpc.NewRequest("eth_blockNumber"))
+				if err != nil {
+					t.Fatalf("expected the retries to get past the dropped connections: %v", err)
+				}
+				checkBlockNumber(t, body)
+
+				reset(mockgeth.Fault{Drop: true, Times: 1})
+				config := testConfig(endpoint)
+				config.Retries = 0
+				noRetry := rpc.NewClient(config)
+				defer noRetry.Close()
+				if _, err := noRetry.ExecuteRequest(context.Background(), rpc.NewRequest("eth_blockNumber")); err == nil {
+					t.Fatal("expected an error without retries")
+				}
+			})
+
+			t.Run("context timeout", func(t *testing.T) {
+				reset(mockgeth.Fault{Delay: time.Second, Times: 1})
+				transport := rpc.NewTransport(testConfig(endpoint))
+				defer transport.Close()
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				start := time.Now()
+				_, err := transport.RoundTrip(ctx, blockNumberRequest(t))
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected the deadline to be exceeded, got %v", err)
+				}
+				if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+					t.Fatalf("the call returned after %s, not at its deadline", elapsed)
+				}
+
+				// a context done right after its call does not leave a
+				// deadline on the pooled connection
+				reset()
+				for i := 0; i < 20; i++ {
+					ctx, cancel := context.WithCancel(context.Background())
+					_, err := transport.RoundTrip(ctx, blockNumberRequest(t))
+					cancel()
+					if err != nil {
+						t.Fatal(err)
+					}
+					body, err := transport.RoundTrip(context.Background(), blockNumberRequest(t))
+					if err != nil {
+						t.Fatalf("call after a canceled context: %v", err)
+					}
+					checkBlockNumber(t, body)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// UncleWindow is the number of ancestors an uncle can branch off from: its
+// parent must be one of the 7 blocks before the block that includes it
+const UncleWindow = 7
+
+// UncleData is what the uncles of a block are checked against: the uncles
+// the node returns by index, and the ancestors the uncles must fit
+type UncleData struct {
+	Uncles      []*types.Header // from eth_getUncleByBlockNumberAndIndex
+	UncleHashes []common.Hash   // hashes the node reports with Uncles
+	// Ancestors are the headers of the blocks before the block, parent
+	// first, and AncestorUncles the uncles they included
+	Ancestors      []*types.Header
+	AncestorUncles []common.Hash
+}
+
+// FetchUncleData fetch the count uncles of a block by index, and the
+// ancestors in the uncle window with the uncles they included, in a single
+// batch. The first failed call is returned as the error.
+func (c *Client) FetchUncleData(ctx context.Context, blockNum uint64, count int) (*UncleData, error) {
+	depth := uint64(UncleWindow)
+	if blockNum < depth {
+		depth = blockNum
+	}
+	uncles := make([]json.RawMessage, count)
+	ancestors := make([]json.RawMessage, depth)
+	batch := make([]BatchElem, 0, count+int(depth))
+	for i := range uncles {
+		batch = append(batch, BatchElem{
+			Method: "eth_getUncleByBlockNumberAndIndex",
+			Params: []interface{}{hexutil.EncodeUint64(blockNum), hexutil.EncodeUint64(uint64(i))},
+			Result: &uncles[i],
+		})
+	}
+	for i := range ancestors {
+		batch = append(batch, BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{hexutil.EncodeUint64(blockNum - uint64(i) - 1), false},
+			Result: &ancestors[i],
+		})
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+	}
+
+	data := &UncleData{
+		Uncles:      make([]*types.Header, count),
+		UncleHashes: make([]common.Hash, count),
+		Ancestors:   make([]*types.Header, depth),
+	}
+	for i, result := range uncles {
+		header, hash, err := decodeBlockHeader("eth_getUncleByBlockNumberAndIndex", result)
+		if err != nil {
+			return nil, fmt.Errorf("uncle %d of block %d: %w", i, blockNum, err)
+		}
+		data.Uncles[i], data.UncleHashes[i] = header, hash
+	}
+	for i, result := range ancestors {
+		header, _, err := decodeBlockHeader("eth_getBlockByNumber", result)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", blockNum-uint64(i)-1, err)
+		}
+		var dec struct {
+			Uncles []common.Hash `json:"uncles"`
+		}
+		if err := json.Unmarshal(result, &dec); err != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber: %w", err)
+		}
+		data.Ancestors[i] = header
+		data.AncestorUncles = append(data.AncestorUncles, dec.Uncles...)
+	}
+	return data, nil
+}
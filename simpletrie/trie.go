@@ -64,8 +64,11 @@ func (t *Trie) Get(key []byte) ([]byte, bool) {
 	}
 }
 
-func (t *Trie) Update(key []byte, value []byte) {
+// Update adds a key value pair to the trie, with the signature of the
+// go-ethereum trie hashers
+func (t *Trie) Update(key []byte, value []byte) error {
 	t.Put(key, value)
+	return nil
 }
 
 // Put adds a key value pair to the trie
@@ -3,17 +3,20 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"os"
+
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
-	"io/ioutil"
-	"os"
-	"strconv"
-	"sync"
-	"transactions-and-receipts/simpletrie"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+	"github.com/KohdMonkey/validate-ethereum-data/hashing"
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/simpletrie"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
 )
 
 const (
@@ -24,137 +27,28 @@ const (
 
 	PostLondonBlockNum      = 15415840
 	PostLondonBlockTxnsRoot = "0xda75f10e5c3ca8adc0c0969da0020377f49f088c436751b735fa8dd4a059ace2"
-)
-
-type TrieUpdater interface {
-	Update([]byte, []byte)
-	Reset()
-}
-
-// TrieHasher is the tool used to calculate the hash of derivable list.
-type TrieHasher interface {
-	Reset()
-	Update([]byte, []byte)
-	Hash() common.Hash
-}
-
-// OldDerivableList is the interface which can derive the hash.
-type OldDerivableList interface {
-	Len() int
-	GetRlp(i int) []byte
-}
-
-// DerivableList is the input to DeriveSha.
-// New DerivableList type
-// It is implemented by the 'Transactions' and 'Receipts' types.
-// This is internal, do not use these methods.
-type DerivableList interface {
-	Len() int
-	EncodeIndex(int, *bytes.Buffer)
-}
-
-func PanicError(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
-
-func TransactionsFromJSON(blockNum int) []*types.Transaction {
-	transactionsFile := DataDir + "/block-" + strconv.Itoa(blockNum) + "-transactions.json"
-	jsonFile, err := os.Open(transactionsFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening file: %v\n", err)
-		os.Exit(1)
-	}
-	defer jsonFile.Close()
-
-	byteValue, err := ioutil.ReadAll(jsonFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading file: %v\n", err)
-		os.Exit(1)
-	}
-
-	var txs []*types.Transaction
-	json.Unmarshal(byteValue, &txs)
-	return txs
-}
-
-// from go-ethereum/core/types/hashing.go
-var encodeBufferPool = sync.Pool{
-	New: func() interface{} { return new(bytes.Buffer) },
-}
-
-// from go-ethereum/core/types/hashing.go
-func encodeForDerive(list DerivableList, i int, buf *bytes.Buffer) []byte {
-	buf.Reset()
-	list.EncodeIndex(i, buf)
-	// It's really unfortunate that we need to do perform this copy.
-	// StackTrie holds onto the values until Hash is called, so the values
-	// written to it must not alias.
-	return common.CopyBytes(buf.Bytes())
-}
-
-// InsertTrieIndexOrder inserting items into a trie by their index
-func InsertTrieIndexOrder(list DerivableList, hasher TrieUpdater) {
-	keybuf := new(bytes.Buffer)
-	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
-	defer encodeBufferPool.Put(valueBuf)
 
-	for i := 0; i < list.Len(); i++ {
-		keybuf.Reset()
-		rlp.Encode(keybuf, uint(i))
-		value := encodeForDerive(list, i, valueBuf)
-
-		hasher.Update(keybuf.Bytes(), value)
-	}
-}
+	// ReceiptsBlockNum contains legacy, access list and dynamic fee receipts
+	ReceiptsBlockNum          = 15129365
+	ReceiptsBlockReceiptsRoot = "0x0595fa2fea554388f3ac06cb67ddc1e644fea876cb0e9d14fac30d266602afe9"
 
-// OldDeriveSha is the old implementation of DeriveSha.
-func OldDeriveSha(list DerivableList, hasher TrieHasher) common.Hash {
-	hasher.Reset()
+	// ShanghaiBlockNum carries withdrawals, its root is read from the block json
+	ShanghaiBlockNum = 18189758
 
-	InsertTrieIndexOrder(list, hasher)
-	return hasher.Hash()
-}
-
-// InsertTrieByteOrder inserts items into a trie by the byte order of its key
-func InsertTrieByteOrder(list DerivableList, hasher TrieUpdater) {
-	hasher.Reset()
-	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
-	defer encodeBufferPool.Put(valueBuf)
-
-	// StackTrie requires values to be inserted in increasing hash order, which is not the
-	// order that `list` provides hashes in. This insertion sequence ensures that the
-	// order is correct.
-	var indexBuf []byte
-	for i := 1; i < list.Len() && i <= 0x7f; i++ {
-		indexBuf = rlp.AppendUint64(indexBuf[:0], uint64(i))
-		value := encodeForDerive(list, i, valueBuf)
-		hasher.Update(indexBuf, value)
-	}
-	if list.Len() > 0 {
-		indexBuf = rlp.AppendUint64(indexBuf[:0], 0)
-		value := encodeForDerive(list, 0, valueBuf)
-		hasher.Update(indexBuf, value)
-	}
-	for i := 0x80; i < list.Len(); i++ {
-		indexBuf = rlp.AppendUint64(indexBuf[:0], uint64(i))
-		value := encodeForDerive(list, i, valueBuf)
-		hasher.Update(indexBuf, value)
-	}
-}
+	// ReceiptsDataDir holds the receipts of ReceiptsBlockNum, shared with
+	// the transactions-and-receipts command
+	ReceiptsDataDir = "../transactions-and-receipts/data"
 
-// DeriveSha creates the tree hashes of transactions and receipts in a block header.
-func DeriveSha(list DerivableList, hasher TrieHasher) common.Hash {
-	InsertTrieByteOrder(list, hasher)
-	return hasher.Hash()
-}
+	// HiveDataDir holds the hive test chain, whose forks from Homestead to
+	// London are a few blocks apart
+	HiveDataDir = "../raw-data/data/hive"
+)
 
 // CheckHash checks whether the root hash from the trie matches
 func CheckHash(expected string, actual []byte) {
 	// expected hash is a hex string, convert to bytes
 	expectedBytes, err := hex.DecodeString(expected[2:])
-	PanicError(err)
+	cmdutil.PanicError(err)
 
 	if !bytes.Equal(expectedBytes, actual) {
 		fmt.Println("ROOT HASH DOES NOT MATCH")
@@ -171,78 +65,270 @@ func CheckHash(expected string, actual []byte) {
 
 // TrieOldShaNewBlock calculates the root hash using the old Trie structure
 // along with the old DeriveSha method and the new Block structure.
-func TrieOldShaNewBlock(txns []*types.Transaction, expectedRoot string) {
-	hasher := new(trie.Trie)
-	txnRootHash := OldDeriveSha(types.Transactions(txns), hasher)
-	CheckHash(expectedRoot, txnRootHash.Bytes())
+func TrieOldShaNewBlock(list hashing.DerivableList, expectedRoot string) {
+	hasher := hashing.NewTrie()
+	rootHash := hashing.OldDeriveSha(list, hasher)
+	CheckHash(expectedRoot, rootHash.Bytes())
 }
 
-func TrieNewShaNewBlock(txns []*types.Transaction, expectedRoot string) {
-	hasher := new(trie.Trie)
-	txnRootHash := DeriveSha(types.Transactions(txns), hasher)
-	CheckHash(expectedRoot, txnRootHash.Bytes())
+func TrieNewShaNewBlock(list hashing.DerivableList, expectedRoot string) {
+	hasher := hashing.NewTrie()
+	rootHash := hashing.DeriveSha(list, hasher)
+	CheckHash(expectedRoot, rootHash.Bytes())
 }
 
-func StackTrieOldShaNewBlock(txns []*types.Transaction, expectedRoot string) {
+func StackTrieOldShaNewBlock(list hashing.DerivableList, expectedRoot string) {
 	hasher := trie.NewStackTrie(nil)
-	txnRootHash := OldDeriveSha(types.Transactions(txns), hasher)
-	CheckHash(expectedRoot, txnRootHash.Bytes())
+	rootHash := hashing.OldDeriveSha(list, hasher)
+	CheckHash(expectedRoot, rootHash.Bytes())
 }
 
-func StackTrieNewShaNewBlock(txns []*types.Transaction, expectedRoot string) {
+func StackTrieNewShaNewBlock(list hashing.DerivableList, expectedRoot string) {
 	hasher := trie.NewStackTrie(nil)
-	txnRootHash := DeriveSha(types.Transactions(txns), hasher)
-	CheckHash(expectedRoot, txnRootHash.Bytes())
+	rootHash := hashing.DeriveSha(list, hasher)
+	CheckHash(expectedRoot, rootHash.Bytes())
 }
 
 // SimpleTrieOldShaNewBlock uses the SimpleTrie structure
-func SimpleTrieOldShaNewBlock(txns []*types.Transaction, expectedRoot string) {
-	list := types.Transactions(txns)
+func SimpleTrieOldShaNewBlock(list hashing.DerivableList, expectedRoot string) {
 	trie := simpletrie.NewTrie()
 
-	InsertTrieIndexOrder(list, trie)
+	hashing.InsertTrieIndexOrder(list, trie)
 	CheckHash(expectedRoot, trie.Hash())
 }
 
-func SimpleTrieNewShaNewBlock(txns []*types.Transaction, expectedRoot string) {
-	list := types.Transactions(txns)
+func SimpleTrieNewShaNewBlock(list hashing.DerivableList, expectedRoot string) {
 	trie := simpletrie.NewTrie()
 
-	InsertTrieByteOrder(list, trie)
+	hashing.InsertTrieByteOrder(list, trie)
 	CheckHash(expectedRoot, trie.Hash())
 }
 
-// TestTrieHash accepts a block and tests the transactions using Trie,
-//StackTrie, and SimpleTrie against the old and new DeriveSha methods.
-func TestTrieHash(blockNum int, txnRoot string) {
-	fmt.Println("Testing trie hash for block", blockNum)
-	txns := TransactionsFromJSON(blockNum)
-	txnRootBytes, err := hex.DecodeString(txnRoot[2:])
-	PanicError(err)
-	fmt.Println("Expected txn root:", txnRootBytes)
+// maxStackTrieIndexOrder is the longest list whose keys a StackTrie accepts
+// in index order
+const maxStackTrieIndexOrder = 128
+
+// TestListHash tests a derivable list using Trie, StackTrie, and SimpleTrie
+// against the old and new DeriveSha methods.
+func TestListHash(list hashing.DerivableList, root string) {
+	rootBytes, err := hex.DecodeString(root[2:])
+	cmdutil.PanicError(err)
+	fmt.Println("Expected root:", rootBytes)
 
 	fmt.Println("Old Trie structure, Old DeriveSha")
-	TrieOldShaNewBlock(txns, txnRoot)
+	TrieOldShaNewBlock(list, root)
 
 	fmt.Println("Old Trie structure, New DeriveSha")
-	TrieNewShaNewBlock(txns, txnRoot)
+	TrieNewShaNewBlock(list, root)
 
+	// StackTrie expects keys in increasing order. Index order only keeps
+	// them apart up to index 127: rlp(128) is 0x8180, below the 0x80 of
+	// index 0 that the StackTrie has already hashed.
 	fmt.Println("StackTrie structure, Old DeriveSha")
-	StackTrieOldShaNewBlock(txns, txnRoot)
+	if list.Len() <= maxStackTrieIndexOrder {
+		StackTrieOldShaNewBlock(list, root)
+	} else {
+		fmt.Printf("skipped: %d items, index order only fits a StackTrie up to %d\n", list.Len(), maxStackTrieIndexOrder)
+	}
 
 	fmt.Println("StackTrie structure, New DeriveSha")
-	StackTrieNewShaNewBlock(txns, txnRoot)
+	StackTrieNewShaNewBlock(list, root)
 
 	fmt.Println("simpletrie structure, old DeriveSha")
-	SimpleTrieOldShaNewBlock(txns, txnRoot)
+	SimpleTrieOldShaNewBlock(list, root)
 
 	fmt.Println("simpletrie structure, new DeriveSha")
-	SimpleTrieNewShaNewBlock(txns, txnRoot)
+	SimpleTrieNewShaNewBlock(list, root)
+}
+
+// TestTrieHash accepts a block and tests the transactions using Trie,
+// StackTrie, and SimpleTrie against the old and new DeriveSha methods.
+func TestTrieHash(blockNum int, txnRoot string) {
+	fmt.Println("Testing trie hash for block", blockNum)
+	txns, err := loader.BlockTransactionsFromJSON(
+		loader.FixturePath(DataDir, blockNum, "transactions"), loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+	TestListHash(types.Transactions(txns), txnRoot)
+}
+
+// PrintReceiptTypes prints how many receipts of each encoding are in the list
+func PrintReceiptTypes(receipts []*types.Receipt) {
+	counts := make(map[uint8]int)
+	for _, receipt := range receipts {
+		counts[receipt.Type]++
+	}
+	fmt.Printf("%-12s: %d\n", "legacy", counts[types.LegacyTxType])
+	fmt.Printf("%-12s: %d\n", "access list", counts[types.AccessListTxType])
+	fmt.Printf("%-12s: %d\n", "dynamic fee", counts[types.DynamicFeeTxType])
+}
+
+// TestReceiptsTrieHash accepts a block and tests the receipts using Trie,
+// StackTrie, and SimpleTrie against the old and new DeriveSha methods.
+func TestReceiptsTrieHash(blockNum int, receiptsRoot string) {
+	fmt.Println("Testing receipts trie hash for block", blockNum)
+	receipts, err := loader.BlockReceiptsFromJSON(
+		loader.FixturePath(ReceiptsDataDir, blockNum, "receipts"), loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+	PrintReceiptTypes(receipts)
+	TestListHash(types.Receipts(receipts), receiptsRoot)
+}
+
+// TestWithdrawalsTrieHash accepts a post-Shanghai block and tests the
+// withdrawals root using StackTrie and SimpleTrie.
+func TestWithdrawalsTrieHash(blockNum int) {
+	fmt.Println("Testing withdrawals trie hash for block", blockNum)
+	withdrawals, withdrawalsRoot, err := loader.WithdrawalsFromJSON(loader.FixturePath(DataDir, blockNum, ""))
+	cmdutil.PanicError(err)
+	fmt.Println("Number of withdrawals:", withdrawals.Len())
+
+	fmt.Println("StackTrie structure, New DeriveSha")
+	StackTrieNewShaNewBlock(withdrawals, withdrawalsRoot.String())
+
+	fmt.Println("simpletrie structure, new DeriveSha")
+	SimpleTrieNewShaNewBlock(withdrawals, withdrawalsRoot.String())
+}
+
+// TestRawBlockBody checks a raw post-Shanghai block against the hash in the
+// block json, and its transactions, uncles and withdrawals against the roots
+// in its header
+func TestRawBlockBody(blockNum int) {
+	fmt.Println("Testing raw block body for block", blockNum)
+	block, err := loader.BlockFromJSON(loader.FixturePath(DataDir, blockNum, ""))
+	cmdutil.PanicError(err)
+	blockBytes, err := loader.RawFromJSON(loader.FixturePath(DataDir, blockNum, "block-rlp"))
+	cmdutil.PanicError(err)
+
+	blockHash, checks, err := verify.CheckRawBlock(blockBytes, block.Hash)
+	if err != nil {
+		fmt.Println("BLOCK HASH DOES NOT MATCH:", err)
+	} else {
+		fmt.Println("BLOCK HASH MATCHES")
+	}
+	fmt.Printf("%-10s: %s\n", "expected", block.Hash)
+	fmt.Printf("%-10s: %s\n", "actual", blockHash)
+	for _, check := range checks {
+		if check.Err != nil {
+			fmt.Printf("%s DOES NOT MATCH: %v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("%s MATCHES: %s\n", check.Name, check.Computed)
+		}
+	}
+	fmt.Println("-------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------")
+}
+
+// TestSenders recovers the senders of the transactions of a block with the
+// signer of its fork and compares them with the "from" addresses
+func TestSenders(config *params.ChainConfig, dataDir string, blockNum int) {
+	path := loader.FixturePath(dataDir, blockNum, "transactions")
+	txns, err := loader.BlockTransactionsFromJSON(path, loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+	senders, err := loader.BlockSendersFromJSON(path, loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+
+	result, err := verify.CheckSenders(config, big.NewInt(int64(blockNum)), txns, senders)
+	if err != nil {
+		fmt.Printf("block %d: SENDERS DO NOT MATCH with the %s signer: %v\n", blockNum, result.Fork, err)
+	} else {
+		fmt.Printf("block %d: %d SENDERS MATCH with the %s signer\n", blockNum, len(txns), result.Fork)
+	}
+	if len(result.HighS) > 0 {
+		fmt.Printf("block %d: high s in transactions %v\n", blockNum, result.HighS)
+	}
+}
+
+// TestTransactionHashes checks the hash and transactionIndex of the
+// transactions of a block, and the transactionHash of its receipts if
+// there is a receipts fixture
+func TestTransactionHashes(dataDir string, blockNum int) {
+	path := loader.FixturePath(dataDir, blockNum, "transactions")
+	txns, err := loader.BlockTransactionsFromJSON(path, loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+	refs, err := loader.BlockTxRefsFromJSON(path, loader.NumberID(uint64(blockNum)))
+	cmdutil.PanicError(err)
+	var receipts []*types.Receipt
+	if receiptsFile := loader.FixturePath(dataDir, blockNum, "receipts"); fileExists(receiptsFile) {
+		receipts, err = loader.BlockReceiptsFromJSON(receiptsFile, loader.NumberID(uint64(blockNum)))
+		cmdutil.PanicError(err)
+	}
+
+	if err := verify.CheckTransactionHashes(txns, refs, receipts); err != nil {
+		fmt.Printf("block %d: TRANSACTION HASHES DO NOT MATCH: %v\n", blockNum, err)
+	} else {
+		fmt.Printf("block %d: %d TRANSACTION HASHES MATCH, %d receipts checked\n", blockNum, len(txns), len(receipts))
+	}
+}
+
+// TestHiveTransactions checks the senders of every block of the hive test
+// chain, with the chain config of its genesis, and counts the transactions
+// checked with the signer of each fork. It also checks the transaction
+// hashes against the transactions and receipts of each block.
+func TestHiveTransactions() {
+	fmt.Println("Testing transactions of the hive test chain")
+	config, err := forks.ChainConfigFromGenesis(HiveDataDir + "/genesis.json")
+	cmdutil.PanicError(err)
+
+	var forks []string
+	counts := make(map[string]int)
+	for n := 0; fileExists(loader.FixturePath(HiveDataDir, n, "transactions")); n++ {
+		path := loader.FixturePath(HiveDataDir, n, "transactions")
+		txns, err := loader.BlockTransactionsFromJSON(path, loader.NumberID(uint64(n)))
+		cmdutil.PanicError(err)
+		senders, err := loader.BlockSendersFromJSON(path, loader.NumberID(uint64(n)))
+		cmdutil.PanicError(err)
+
+		result, err := verify.CheckSenders(config, big.NewInt(int64(n)), txns, senders)
+		if err != nil {
+			fmt.Printf("block %d: SENDERS DO NOT MATCH with the %s signer: %v\n", n, result.Fork, err)
+		}
+		if _, ok := counts[result.Fork]; !ok {
+			forks = append(forks, result.Fork)
+		}
+		counts[result.Fork] += len(txns)
+
+		refs, err := loader.BlockTxRefsFromJSON(path, loader.NumberID(uint64(n)))
+		cmdutil.PanicError(err)
+		receipts, err := loader.BlockReceiptsFromJSON(loader.FixturePath(HiveDataDir, n, "receipts"), loader.NumberID(uint64(n)))
+		cmdutil.PanicError(err)
+		if err := verify.CheckTransactionHashes(txns, refs, receipts); err != nil {
+			fmt.Printf("block %d: TRANSACTION HASHES DO NOT MATCH: %v\n", n, err)
+		}
+	}
+	for _, fork := range forks {
+		fmt.Printf("%-10s: %d transactions checked\n", fork, counts[fork])
+	}
+	fmt.Println("-------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------")
+}
+
+// fileExists reports whether a fixture is present
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 func main() {
-	// TODO: prelondon blocks trigger "hashedNode" for StackTrie insert
-	//TestTrieHash(PreLondonBlockNum, PreLondonTxnsRoot)
+	TestTrieHash(PreLondonBlockNum, PreLondonTxnsRoot)
 
 	TestTrieHash(PostLondonBlockNum, PostLondonBlockTxnsRoot)
+
+	TestReceiptsTrieHash(ReceiptsBlockNum, ReceiptsBlockReceiptsRoot)
+
+	TestWithdrawalsTrieHash(ShanghaiBlockNum)
+
+	TestRawBlockBody(ShanghaiBlockNum)
+
+	fmt.Println("Testing senders of mainnet blocks")
+	TestSenders(params.MainnetChainConfig, DataDir, PreLondonBlockNum)
+	TestSenders(params.MainnetChainConfig, DataDir, PostLondonBlockNum)
+	fmt.Println("-------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------")
+
+	fmt.Println("Testing transaction hashes of mainnet blocks")
+	TestTransactionHashes(DataDir, PreLondonBlockNum)
+	TestTransactionHashes(DataDir, PostLondonBlockNum)
+	fmt.Println("-------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------")
+
+	TestHiveTransactions()
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KohdMonkey/validate-ethereum-data/internal/cmdutil"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/proof"
+)
+
+const _DefaultBlock = "../raw-data/data/block-15209997.json"
+
+var (
+	blockFile  = flag.String("block", _DefaultBlock, "block from eth_getBlockByNumber with full transactions")
+	txIndex    = flag.Int("index", -1, "index of the transaction to prove")
+	txHash     = flag.String("hash", "", "hash of the transaction to prove, instead of -index")
+	outFile    = flag.String("out", "", "file to write the proof to (default tx-proof-<block>-<index>.json)")
+	verifyFile = flag.String("verify", "", "only verify an existing proof file against the block header")
+)
+
+// findTransaction returns the index of the transaction selected by the flags
+func findTransaction(block *loader.Block) int {
+	if *txHash == "" {
+		if *txIndex < 0 {
+			cmdutil.ExitError("either -index or -hash is required")
+		}
+		return *txIndex
+	}
+
+	hash := common.HexToHash(*txHash)
+	for i, tx := range block.Transactions {
+		if tx.Hash() == hash {
+			return i
+		}
+	}
+	cmdutil.ExitError(fmt.Sprintf("transaction %s is not in block %d", hash, block.Header.Number))
+	return -1
+}
+
+// VerifyProofFile verify a proof file on its own against the transactions
+// root of the block header
+func VerifyProofFile(path string, block *loader.Block) {
+	fmt.Println("Verifying proof", path)
+	p, err := proof.ReadFile(path)
+	cmdutil.PanicError(err)
+
+	if err := p.Verify(block.Header.TxHash); err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+
+	fmt.Println("proof matches")
+	fmt.Println("Transaction: ", p.TransactionHash.String(), "at index", uint64(p.TransactionIndex))
+	fmt.Println("Transactions root: ", block.Header.TxHash.String())
+}
+
+func main() {
+	flag.Parse()
+
+	block, err := loader.BlockFromJSON(*blockFile)
+	cmdutil.PanicError(err)
+
+	if *verifyFile != "" {
+		VerifyProofFile(*verifyFile, block)
+		return
+	}
+
+	index := findTransaction(block)
+	fmt.Println("Proving transaction", index, "of block", block.Header.Number)
+	p, err := proof.ProveTransaction(block.Header.Number.Uint64(), block.Transactions, uint64(index))
+	if err != nil {
+		cmdutil.ExitError(err.Error())
+	}
+	fmt.Println("Proof nodes: ", len(p.Proof))
+
+	path := *outFile
+	if path == "" {
+		path = fmt.Sprintf("tx-proof-%d-%d.json", block.Header.Number, index)
+	}
+	cmdutil.PanicError(p.WriteFile(path))
+	fmt.Println("Proof written to", path)
+	fmt.Println("----------------------------------------------------")
+
+	VerifyProofFile(path, block)
+}
@@ -0,0 +1,104 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// BloomDiff is an encoded bloom that differs from the bloom of the logs it
+// covers
+type BloomDiff struct {
+	Receipt  int // index of the receipt, -1 for the logsBloom of the header
+	Computed types.Bloom
+	Encoded  types.Bloom
+}
+
+func (d BloomDiff) String() string {
+	name := fmt.Sprintf("receipt %d logsBloom", d.Receipt)
+	if d.Receipt < 0 {
+		name = "header logsBloom"
+	}
+	computed, encoded := hexutil.Encode(d.Computed[:]), hexutil.Encode(d.Encoded[:])
+	return fmt.Sprintf("%s: from logs %s, encoded %s", name, shorten(computed, encoded), shorten(encoded, computed))
+}
+
+// BloomError is returned when the logs bloom of a receipt, or of the header,
+// is not the bloom recomputed from the logs
+type BloomError struct {
+	Diffs []BloomDiff
+}
+
+func (e *BloomError) Error() string {
+	diffs := make([]string, len(e.Diffs))
+	for i, diff := range e.Diffs {
+		diffs[i] = diff.String()
+	}
+	return "logs bloom does not match the logs: " + strings.Join(diffs, "; ")
+}
+
+// Receipts returns the indexes of the receipts whose bloom is wrong
+func (e *BloomError) Receipts() []int {
+	var receipts []int
+	for _, diff := range e.Diffs {
+		if diff.Receipt >= 0 {
+			receipts = append(receipts, diff.Receipt)
+		}
+	}
+	return receipts
+}
+
+// VerifyLogsBloom fetch the rlp-encoded receipts of a block from the client
+// and check their blooms, and the logsBloom of the header, against the logs
+func VerifyLogsBloom(ctx context.Context, client *rpc.Client, blockNum uint64, receiptsFromJson []*types.Receipt, headerBloom types.Bloom) (types.Bloom, error) {
+	receiptsBytesArr, err := client.GetRawReceipts(ctx, blockNum)
+	if err != nil {
+		return types.Bloom{}, err
+	}
+	return CheckLogsBloom(receiptsBytesArr, receiptsFromJson, headerBloom)
+}
+
+// CheckLogsBloom recompute the bloom of each receipt from its logs, compare
+// it with the bloom encoded in the raw receipt, and compare the blooms ORed
+// together with the logsBloom of the header. The logs are taken from the
+// json receipts, or from the raw receipts when there are none. It returns
+// the recomputed block bloom.
+func CheckLogsBloom(receiptsBytesArr [][]byte, receiptsFromJson []*types.Receipt, headerBloom types.Bloom) (types.Bloom, error) {
+	receipts, err := loader.BytesToReceipts(receiptsBytesArr)
+	if err != nil {
+		return types.Bloom{}, err
+	}
+	if receiptsFromJson != nil && len(receiptsFromJson) != len(receipts) {
+		return types.Bloom{}, fmt.Errorf("%d receipts from json, %d receipts from rpc",
+			len(receiptsFromJson), len(receipts))
+	}
+
+	var blockBloom types.Bloom
+	var diffs []BloomDiff
+	for i, receipt := range receipts {
+		logs := receipt.Logs
+		if receiptsFromJson != nil {
+			logs = receiptsFromJson[i].Logs
+		}
+		bloom := types.BytesToBloom(types.LogsBloom(logs))
+		if bloom != receipt.Bloom {
+			diffs = append(diffs, BloomDiff{Receipt: i, Computed: bloom, Encoded: receipt.Bloom})
+		}
+		for j := range blockBloom {
+			blockBloom[j] |= bloom[j]
+		}
+	}
+	if blockBloom != headerBloom {
+		diffs = append(diffs, BloomDiff{Receipt: -1, Computed: blockBloom, Encoded: headerBloom})
+	}
+	if len(diffs) > 0 {
+		return blockBloom, &BloomError{Diffs: diffs}
+	}
+	return blockBloom, nil
+}
@@ -0,0 +1,60 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ChainBreak is returned for the first header of a segment that does not
+// follow the header before it
+type ChainBreak struct {
+	Index  int    // index of the header in the segment
+	Number uint64 // number of the header
+	Reason string
+}
+
+func (e *ChainBreak) Error() string {
+	return fmt.Sprintf("header %d (#%d) breaks the chain: %s", e.Index, e.Number, e.Reason)
+}
+
+// CheckHeaderChain check that each header of a segment follows the one
+// before it: its parentHash is the hash of that header, its number is one
+// more and its timestamp is later. It returns the first break.
+func CheckHeaderChain(headers []*types.Header) error {
+	for i := 1; i < len(headers); i++ {
+		parent, header := headers[i-1], headers[i]
+		number := header.Number.Uint64()
+		if hash := parent.Hash(); header.ParentHash != hash {
+			return &ChainBreak{Index: i, Number: number,
+				Reason: fmt.Sprintf("parentHash %s, hash of header %d is %s", header.ParentHash, i-1, hash)}
+		}
+		if parentNumber := parent.Number.Uint64(); number != parentNumber+1 {
+			return &ChainBreak{Index: i, Number: number,
+				Reason: fmt.Sprintf("number does not follow %d", parentNumber)}
+		}
+		if header.Time <= parent.Time {
+			return &ChainBreak{Index: i, Number: number,
+				Reason: fmt.Sprintf("timestamp %d, not after %d", header.Time, parent.Time)}
+		}
+	}
+	return nil
+}
+
+// CheckAnchoredChain check a segment of headers that ends in the header
+// with the trusted hash. The parent hashes link every header before it to
+// that hash, so the whole segment is as trusted as the hash.
+func CheckAnchoredChain(headers []*types.Header, trusted common.Hash) error {
+	if len(headers) == 0 {
+		return fmt.Errorf("no headers in the segment")
+	}
+	if err := CheckHeaderChain(headers); err != nil {
+		return err
+	}
+	last := headers[len(headers)-1]
+	if hash := last.Hash(); hash != trusted {
+		return fmt.Errorf("segment ends in #%d %s, trusted hash %s", last.Number, hash, trusted)
+	}
+	return nil
+}
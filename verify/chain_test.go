@@ -0,0 +1,116 @@
+package verify_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+// hiveHeaders loads the headers of the first blocks of the hive chain
+func hiveHeaders(t *testing.T, count int) []*types.Header {
+	t.Helper()
+	headers := make([]*types.Header, count)
+	for i := range headers {
+		var err error
+		if headers[i], err = loader.HeaderFromJSON(loader.FixturePath(hiveDir, i, "header")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return headers
+}
+
+func TestCheckHeaderChain(t *testing.T) {
+	const (
+		count = 10
+		index = 6 // header broken by the test cases
+	)
+	tests := []struct {
+		name   string
+		change func(headers []*types.Header)
+		err    string // part of the break reason, no error when empty
+	}{
+		{name: "hive chain"},
+		{
+			name:   "missing header",
+			change: func(headers []*types.Header) { headers[index] = headers[index+1] },
+			err:    "parentHash",
+		},
+		{
+			name: "header of another parent",
+			change: func(headers []*types.Header) {
+				headers[index] = types.CopyHeader(headers[index])
+				headers[index].ParentHash = common.HexToHash("0x01")
+			},
+			err: "parentHash 0x0000000000000000000000000000000000000000000000000000000000000001",
+		},
+		{
+			name: "wrong number",
+			change: func(headers []*types.Header) {
+				headers[index] = types.CopyHeader(headers[index])
+				headers[index].Number.SetUint64(index + 1)
+			},
+			err: "number does not follow 5",
+		},
+		{
+			name: "timestamp of the parent",
+			change: func(headers []*types.Header) {
+				headers[index] = types.CopyHeader(headers[index])
+				headers[index].Time = headers[index-1].Time
+			},
+			err: "not after",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			headers := hiveHeaders(t, count)
+			if test.change != nil {
+				test.change(headers)
+			}
+			err := verify.CheckHeaderChain(headers)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var chainBreak *verify.ChainBreak
+			if !errors.As(err, &chainBreak) {
+				t.Fatalf("error %v, want a ChainBreak", err)
+			}
+			if chainBreak.Index != index || !strings.Contains(chainBreak.Reason, test.err) {
+				t.Fatalf("break at %d: %s, want at %d with %q", chainBreak.Index, chainBreak.Reason, index, test.err)
+			}
+		})
+	}
+}
+
+func TestCheckAnchoredChain(t *testing.T) {
+	const count = 10
+	headers := hiveHeaders(t, count)
+	trusted := headers[count-1].Hash()
+	if err := verify.CheckAnchoredChain(headers, trusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := verify.CheckAnchoredChain(headers, common.HexToHash("0x01"))
+	if err == nil || !strings.Contains(err.Error(), "segment ends in #9 "+trusted.String()) {
+		t.Fatalf("wrong trusted hash: error %v, want the segment to end elsewhere", err)
+	}
+
+	broken := hiveHeaders(t, count)
+	broken[3] = broken[4]
+	var chainBreak *verify.ChainBreak
+	if err := verify.CheckAnchoredChain(broken, trusted); !errors.As(err, &chainBreak) || chainBreak.Index != 3 {
+		t.Fatalf("break in the segment: error %v, want a ChainBreak at 3", err)
+	}
+	if err := verify.CheckAnchoredChain(nil, trusted); err == nil {
+		t.Fatal("no headers: no error")
+	}
+}
@@ -0,0 +1,165 @@
+package verify
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// maxGasLimit is the largest gas limit a header can have, 2^63-1
+const maxGasLimit = uint64(0x7fffffffffffffff)
+
+// CheckHeaderRules check the consensus rules a header must follow against
+// its parent, for the forks active at its number: the gas limit, the gas
+// used, the base fee and the extraData size, then the difficulty adjustment
+// before the merge, or the fixed proof-of-stake fields after it. parentTD is
+// the total difficulty of the parent, nil when it is not known; it places
+// the header before or after the terminal total difficulty. A header can
+// hash correctly and still break these rules. A header of Cancun or later
+// only fails a "fork" check, with a forks.ErrUnsupported.
+func CheckHeaderRules(schedule Schedule, header, parent *types.Header, parentTD *big.Int) []Check {
+	merged := schedule.IsMerged(header, parentTD)
+	if err := schedule.CheckSupported(header, merged); err != nil {
+		return []Check{{Name: "fork", Err: err}}
+	}
+	config := schedule.Config
+	checks := []Check{
+		{Name: "gas limit", Err: checkGasLimit(config, header, parent)},
+		{Name: "gas used", Err: checkGasUsed(header)},
+		{Name: "base fee", Err: checkBaseFee(config, header, parent)},
+		{Name: "extra data", Err: checkExtraData(header)},
+	}
+	if !merged {
+		return append(checks,
+			Check{Name: "difficulty", Err: checkDifficulty(config, header, parent)},
+			Check{Name: "terminal total difficulty", Err: checkBeforeMerge(config, parentTD)},
+		)
+	}
+	return append(checks, CheckMergeRules(header)...)
+}
+
+// CheckMergeRules check the fields a proof-of-stake header has fixed: no
+// difficulty, a zero nonce and no uncles. The mixHash holds the prevRandao
+// of the beacon chain, which has no rule here, and is no longer a seal.
+func CheckMergeRules(header *types.Header) []Check {
+	return []Check{
+		{Name: "difficulty", Err: checkMergeDifficulty(header)},
+		{Name: "nonce", Err: checkMergeNonce(header)},
+		{Name: "uncles hash", Err: checkMergeUncles(header)},
+	}
+}
+
+// checkBeforeMerge check that a proof-of-work header comes before the
+// terminal total difficulty: its parent must be below it
+func checkBeforeMerge(config *params.ChainConfig, parentTD *big.Int) error {
+	ttd := config.TerminalTotalDifficulty
+	if ttd == nil || parentTD == nil || parentTD.Cmp(ttd) < 0 {
+		return nil
+	}
+	return fmt.Errorf("proof-of-work header after the terminal total difficulty %d, parent total difficulty %d",
+		ttd, parentTD)
+}
+
+// checkMergeDifficulty check that a proof-of-stake header has no difficulty
+func checkMergeDifficulty(header *types.Header) error {
+	if header.Difficulty == nil || header.Difficulty.Sign() != 0 {
+		return fmt.Errorf("difficulty %s after the merge, expected 0", formatValue(header.Difficulty))
+	}
+	return nil
+}
+
+// checkMergeNonce check that a proof-of-stake header has a zero nonce
+func checkMergeNonce(header *types.Header) error {
+	if header.Nonce != (types.BlockNonce{}) {
+		return fmt.Errorf("nonce %#x after the merge, expected 0", header.Nonce.Uint64())
+	}
+	return nil
+}
+
+// checkMergeUncles check that a proof-of-stake header has the hash of an
+// empty uncle list
+func checkMergeUncles(header *types.Header) error {
+	if header.UncleHash != types.EmptyUncleHash {
+		return fmt.Errorf("sha3Uncles %s after the merge, expected the empty list %s", header.UncleHash, types.EmptyUncleHash)
+	}
+	return nil
+}
+
+// checkGasLimit check that the gas limit moved by less than 1/1024 of the
+// parent gas limit and stays above the minimum. At the London block the
+// parent gas limit is doubled by the elasticity multiplier first, as the
+// gas target of the parent becomes the gas limit.
+func checkGasLimit(config *params.ChainConfig, header, parent *types.Header) error {
+	if header.GasLimit > maxGasLimit {
+		return fmt.Errorf("gasLimit %d, above the maximum %d", header.GasLimit, maxGasLimit)
+	}
+	parentGasLimit := parent.GasLimit
+	if config.IsLondon(header.Number) && !config.IsLondon(parent.Number) {
+		parentGasLimit = parent.GasLimit * config.ElasticityMultiplier()
+	}
+	diff := int64(header.GasLimit) - int64(parentGasLimit)
+	if diff < 0 {
+		diff = -diff
+	}
+	if limit := parentGasLimit / params.GasLimitBoundDivisor; uint64(diff) >= limit {
+		return fmt.Errorf("gasLimit %d moved by %d from parent gasLimit %d, the bound is below %d",
+			header.GasLimit, diff, parentGasLimit, limit)
+	}
+	if header.GasLimit < params.MinGasLimit {
+		return fmt.Errorf("gasLimit %d, below the minimum %d", header.GasLimit, params.MinGasLimit)
+	}
+	return nil
+}
+
+// checkGasUsed check that the block used at most its gas limit
+func checkGasUsed(header *types.Header) error {
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("gasUsed %d, above gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	return nil
+}
+
+// checkBaseFee check the EIP-1559 base fee: none before London, the initial
+// base fee at the London block, and after it the base fee of the parent
+// adjusted by how far its gas used was from its gas target
+func checkBaseFee(config *params.ChainConfig, header, parent *types.Header) error {
+	if !config.IsLondon(header.Number) {
+		if header.BaseFee != nil {
+			return fmt.Errorf("baseFeePerGas %d before London", header.BaseFee)
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return fmt.Errorf("missing baseFeePerGas")
+	}
+	if expected := misc.CalcBaseFee(config, parent); header.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("baseFeePerGas %d, expected %d from parent baseFeePerGas %s, gasUsed %d and gasLimit %d",
+			header.BaseFee, expected, formatValue(parent.BaseFee), parent.GasUsed, parent.GasLimit)
+	}
+	return nil
+}
+
+// checkExtraData check that extraData fits in 32 bytes
+func checkExtraData(header *types.Header) error {
+	if size := uint64(len(header.Extra)); size > params.MaximumExtraDataSize {
+		return fmt.Errorf("extraData of %d bytes, at most %d are allowed", size, params.MaximumExtraDataSize)
+	}
+	return nil
+}
+
+// checkDifficulty check the difficulty of a proof-of-work header against
+// the difficulty adjustment of its fork, from Frontier to Gray Glacier
+func checkDifficulty(config *params.ChainConfig, header, parent *types.Header) error {
+	if header.Difficulty == nil {
+		return fmt.Errorf("missing difficulty")
+	}
+	if expected := ethash.CalcDifficulty(config, header.Time, parent); header.Difficulty.Cmp(expected) != 0 {
+		return fmt.Errorf("difficulty %d, expected %d from parent difficulty %d and %d seconds between the blocks",
+			header.Difficulty, expected, parent.Difficulty, int64(header.Time)-int64(parent.Time))
+	}
+	return nil
+}
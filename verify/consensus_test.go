@@ -0,0 +1,132 @@
+package verify_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+const (
+	londonBlock    = 12965000
+	parentGasLimit = 15000000
+)
+
+// headerPair is a header and its parent
+type headerPair struct{ header, parent *types.Header }
+
+// newHeaderPair returns a proof-of-work header at number and its parent,
+// with the gas limit of the header and the base fee of each set
+func newHeaderPair(number int64, parentBaseFee, baseFee *big.Int, gasLimit uint64) headerPair {
+	parent := &types.Header{
+		Number:     big.NewInt(number - 1),
+		Difficulty: big.NewInt(1),
+		GasLimit:   parentGasLimit,
+		GasUsed:    parentGasLimit / 2,
+		BaseFee:    parentBaseFee,
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(number),
+		Difficulty: big.NewInt(1),
+		GasLimit:   gasLimit,
+		BaseFee:    baseFee,
+	}
+	return headerPair{header: header, parent: parent}
+}
+
+func TestCheckGasLimitAndBaseFee(t *testing.T) {
+	schedule, err := verify.LoadSchedule("mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialBaseFee := big.NewInt(params.InitialBaseFee)
+	// the parent of a London block used half its gas limit, its gas target,
+	// so the base fee does not move
+	baseFee := big.NewInt(30000000000)
+	bound := uint64(parentGasLimit / params.GasLimitBoundDivisor)
+	elasticity := schedule.Config.ElasticityMultiplier()
+
+	tests := []struct {
+		name     string
+		headers  headerPair
+		gasLimit string // part of the gas limit error, no error when empty
+		baseFee  string // part of the base fee error, no error when empty
+	}{
+		{
+			name:    "before London",
+			headers: newHeaderPair(londonBlock-1, nil, nil, parentGasLimit+bound-1),
+		},
+		{
+			name:    "base fee before London",
+			headers: newHeaderPair(londonBlock-1, nil, initialBaseFee, parentGasLimit),
+			baseFee: "before London",
+		},
+		{
+			name:    "first London block",
+			headers: newHeaderPair(londonBlock, nil, initialBaseFee, parentGasLimit*elasticity),
+		},
+		{
+			name:     "first London block without the elasticity multiplier",
+			headers:  newHeaderPair(londonBlock, nil, initialBaseFee, parentGasLimit),
+			gasLimit: "from parent gasLimit 30000000",
+		},
+		{
+			name:    "first London block with a wrong initial base fee",
+			headers: newHeaderPair(londonBlock, nil, baseFee, parentGasLimit*elasticity),
+			baseFee: "expected 1000000000",
+		},
+		{
+			name:    "first London block without a base fee",
+			headers: newHeaderPair(londonBlock, nil, nil, parentGasLimit*elasticity),
+			baseFee: "missing baseFeePerGas",
+		},
+		{
+			name:    "gas limit just inside the bound",
+			headers: newHeaderPair(londonBlock+1, baseFee, baseFee, parentGasLimit-bound+1),
+		},
+		{
+			name:     "gas limit raised by parent/1024",
+			headers:  newHeaderPair(londonBlock+1, baseFee, baseFee, parentGasLimit+bound),
+			gasLimit: "the bound is below 14648",
+		},
+		{
+			name:     "gas limit lowered by parent/1024",
+			headers:  newHeaderPair(londonBlock+1, baseFee, baseFee, parentGasLimit-bound),
+			gasLimit: "the bound is below 14648",
+		},
+		{
+			name:    "wrong base fee",
+			headers: newHeaderPair(londonBlock+1, baseFee, new(big.Int).Add(baseFee, big.NewInt(1)), parentGasLimit),
+			baseFee: "expected 30000000000",
+		},
+	}
+	for _, test := range tests {
+		checks := verify.CheckHeaderRules(schedule, test.headers.header, test.headers.parent, nil)
+		for _, want := range []struct{ check, err string }{{"gas limit", test.gasLimit}, {"base fee", test.baseFee}} {
+			err := findCheck(t, checks, want.check)
+			switch {
+			case want.err == "" && err != nil:
+				t.Errorf("%s: %s: unexpected error: %v", test.name, want.check, err)
+			case want.err != "" && (err == nil || !strings.Contains(err.Error(), want.err)):
+				t.Errorf("%s: %s: error %v, want it to contain %q", test.name, want.check, err, want.err)
+			}
+		}
+	}
+}
+
+// findCheck returns the error of the named check
+func findCheck(t *testing.T, checks []verify.Check, name string) error {
+	t.Helper()
+	for _, check := range checks {
+		if check.Name == name {
+			return check.Err
+		}
+	}
+	t.Fatalf("no %s check in %v", name, checks)
+	return nil
+}
@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// VerifyReceiptConsistency fetch the rlp-encoded block and receipts from the
+// client and check that the receipts fit the transactions of the block and
+// its header
+func VerifyReceiptConsistency(ctx context.Context, client *rpc.Client, blockNum uint64, header *types.Header, blockHash common.Hash, receiptsFromJson []*types.Receipt) error {
+	blockBytes, err := client.GetBlockRlp(ctx, blockNum)
+	if err != nil {
+		return err
+	}
+	block, err := loader.SplitRawBlock(blockBytes)
+	if err != nil {
+		return err
+	}
+	txs, err := block.DecodeTransactions()
+	if err != nil {
+		return err
+	}
+	receiptsBytesArr, err := client.GetRawReceipts(ctx, blockNum)
+	if err != nil {
+		return err
+	}
+	receipts, err := loader.BytesToReceipts(receiptsBytesArr)
+	if err != nil {
+		return err
+	}
+	return CheckReceiptConsistency(header, blockHash, txs, receipts, receiptsFromJson)
+}
+
+// CheckReceiptConsistency check that the receipts of a block fit its
+// transactions and header: one receipt per transaction, of the same type,
+// with a cumulativeGasUsed that strictly increases up to the gasUsed of the
+// header. receipts are the raw receipts, the consensus data. The json
+// receipts, when given, must also place each receipt at its transaction in
+// the block, and report the gas used by each transaction.
+func CheckReceiptConsistency(header *types.Header, blockHash common.Hash, txs []*types.Transaction, receipts []*types.Receipt, receiptsFromJson []*types.Receipt) error {
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("%d receipts for %d transactions", len(receipts), len(txs))
+	}
+	if receiptsFromJson != nil && len(receiptsFromJson) != len(receipts) {
+		return fmt.Errorf("%d receipts from json, %d receipts from rpc", len(receiptsFromJson), len(receipts))
+	}
+
+	var problems []string
+	var cumulative uint64
+	for i, receipt := range receipts {
+		prefix := fmt.Sprintf("receipt %d", i)
+		if receipt.Type != txs[i].Type() {
+			problems = append(problems, fmt.Sprintf("%s: type %d, transaction type %d", prefix, receipt.Type, txs[i].Type()))
+		}
+		if receipt.CumulativeGasUsed <= cumulative {
+			problems = append(problems, fmt.Sprintf("%s: cumulativeGasUsed %d, not above %d of the receipt before",
+				prefix, receipt.CumulativeGasUsed, cumulative))
+		} else if receiptsFromJson != nil {
+			if used := receipt.CumulativeGasUsed - cumulative; receiptsFromJson[i].GasUsed != used {
+				problems = append(problems, fmt.Sprintf("%s: gasUsed %d, cumulativeGasUsed grows by %d",
+					prefix, receiptsFromJson[i].GasUsed, used))
+			}
+		}
+		if receipt.CumulativeGasUsed > cumulative {
+			cumulative = receipt.CumulativeGasUsed
+		}
+		if receiptsFromJson != nil {
+			problems = append(problems, checkReceiptPlace(prefix, receiptsFromJson[i], uint(i), header, blockHash)...)
+		}
+	}
+	if cumulative != header.GasUsed {
+		problems = append(problems, fmt.Sprintf("last cumulativeGasUsed %d, header gasUsed %d", cumulative, header.GasUsed))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkReceiptPlace check the fields of a json receipt that place it in the
+// block
+func checkReceiptPlace(prefix string, receipt *types.Receipt, index uint, header *types.Header, blockHash common.Hash) []string {
+	var problems []string
+	if receipt.TransactionIndex != index {
+		problems = append(problems, fmt.Sprintf("%s: transactionIndex %d", prefix, receipt.TransactionIndex))
+	}
+	if receipt.BlockHash != blockHash {
+		problems = append(problems, fmt.Sprintf("%s: blockHash %s, block hash %s", prefix, receipt.BlockHash, blockHash))
+	}
+	if receipt.BlockNumber == nil || receipt.BlockNumber.Cmp(header.Number) != 0 {
+		problems = append(problems, fmt.Sprintf("%s: blockNumber %s, block number %d",
+			prefix, formatValue(receipt.BlockNumber), header.Number))
+	}
+	return problems
+}
@@ -0,0 +1,114 @@
+package verify_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+// fixtureBlock is the fixture block decoded from json, loaded again for each
+// test case so that changes do not leak
+type fixtureBlock struct {
+	header   *types.Header
+	txs      []*types.Transaction
+	receipts []*types.Receipt // the consensus receipts
+	json     []*types.Receipt // the json receipts, with the fields placing them
+}
+
+func loadFixtureBlock(t *testing.T) fixtureBlock {
+	t.Helper()
+	var (
+		b   fixtureBlock
+		err error
+	)
+	if b.header, err = loader.HeaderFromJSON(loader.FixturePath(dataDir, blockNum, "header")); err != nil {
+		t.Fatal(err)
+	}
+	if b.txs, err = loader.BlockTransactionsFromJSON(loader.FixturePath(dataDir, blockNum, "transactions"), loader.NumberID(blockNum)); err != nil {
+		t.Fatal(err)
+	}
+	receiptsFile := loader.FixturePath(dataDir, blockNum, "receipts")
+	if b.receipts, err = loader.BlockReceiptsFromJSON(receiptsFile, loader.NumberID(blockNum)); err != nil {
+		t.Fatal(err)
+	}
+	if b.json, err = loader.BlockReceiptsFromJSON(receiptsFile, loader.NumberID(blockNum)); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCheckReceiptConsistency(t *testing.T) {
+	const index = 3 // receipt changed by the test cases
+	tests := []struct {
+		name   string
+		change func(b *fixtureBlock)
+		err    string // part of the error, no error when empty
+	}{
+		{name: "fixture block"},
+		{
+			name:   "receipt type of another transaction type",
+			change: func(b *fixtureBlock) { b.receipts[index].Type = b.txs[index].Type() ^ 1 },
+			err:    "receipt 3: type",
+		},
+		{
+			name:   "wrong transactionIndex",
+			change: func(b *fixtureBlock) { b.json[index].TransactionIndex = index + 1 },
+			err:    "receipt 3: transactionIndex 4",
+		},
+		{
+			name:   "wrong blockHash",
+			change: func(b *fixtureBlock) { b.json[index].BlockHash = common.HexToHash("0x01") },
+			err:    "receipt 3: blockHash 0x0000000000000000000000000000000000000000000000000000000000000001",
+		},
+		{
+			name:   "wrong blockNumber",
+			change: func(b *fixtureBlock) { b.json[index].BlockNumber = big.NewInt(blockNum + 1) },
+			err:    "receipt 3: blockNumber 15209998",
+		},
+		{
+			name: "cumulativeGasUsed not increasing",
+			change: func(b *fixtureBlock) {
+				b.receipts[index].CumulativeGasUsed = b.receipts[index-1].CumulativeGasUsed
+			},
+			err: "receipt 3: cumulativeGasUsed",
+		},
+		{
+			name:   "gasUsed of the json receipt",
+			change: func(b *fixtureBlock) { b.json[index].GasUsed++ },
+			err:    "receipt 3: gasUsed",
+		},
+		{
+			name:   "last cumulativeGasUsed differs from the header gasUsed",
+			change: func(b *fixtureBlock) { b.header.GasUsed++ },
+			err:    "last cumulativeGasUsed",
+		},
+		{
+			name:   "one receipt fewer than transactions",
+			change: func(b *fixtureBlock) { b.receipts = b.receipts[1:] },
+			err:    "receipts for",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b := loadFixtureBlock(t)
+			hash := b.header.Hash()
+			if test.change != nil {
+				test.change(&b)
+			}
+			err := verify.CheckReceiptConsistency(b.header, hash, b.txs, b.receipts, b.json)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package verify
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FieldDiff is a field that differs between the data decoded from json and
+// the data decoded from the raw rpc bytes
+type FieldDiff struct {
+	Field string // json name of the field, with its position, e.g. "receipt 3 log 1 topics"
+	Json  string
+	Rpc   string
+}
+
+func (d FieldDiff) String() string {
+	j, r := shorten(d.Json, d.Rpc), shorten(d.Rpc, d.Json)
+	return fmt.Sprintf("%s: json %s, rpc %s", d.Field, j, r)
+}
+
+// MismatchError is returned when the encoding of the json data differs from
+// the raw bytes from the client, with the fields that differ
+type MismatchError struct {
+	Message string
+	Diffs   []FieldDiff
+}
+
+func (e *MismatchError) Error() string {
+	if len(e.Diffs) == 0 {
+		return e.Message + ": the decoded fields are equal, only the encodings differ"
+	}
+	diffs := make([]string, len(e.Diffs))
+	for i, diff := range e.Diffs {
+		diffs[i] = diff.String()
+	}
+	return e.Message + ": " + strings.Join(diffs, "; ")
+}
+
+// DiffHeaders returns the fields of the rpc header that differ from the json
+// header
+func DiffHeaders(fromJson, fromRpc *types.Header) []FieldDiff {
+	d := differ{}
+	d.field("parentHash", fromJson.ParentHash, fromRpc.ParentHash)
+	d.field("sha3Uncles", fromJson.UncleHash, fromRpc.UncleHash)
+	d.field("miner", fromJson.Coinbase, fromRpc.Coinbase)
+	d.field("stateRoot", fromJson.Root, fromRpc.Root)
+	d.field("transactionsRoot", fromJson.TxHash, fromRpc.TxHash)
+	d.field("receiptsRoot", fromJson.ReceiptHash, fromRpc.ReceiptHash)
+	d.field("logsBloom", fromJson.Bloom, fromRpc.Bloom)
+	d.field("difficulty", fromJson.Difficulty, fromRpc.Difficulty)
+	d.field("number", fromJson.Number, fromRpc.Number)
+	d.field("gasLimit", fromJson.GasLimit, fromRpc.GasLimit)
+	d.field("gasUsed", fromJson.GasUsed, fromRpc.GasUsed)
+	d.field("timestamp", fromJson.Time, fromRpc.Time)
+	d.field("extraData", fromJson.Extra, fromRpc.Extra)
+	d.field("mixHash", fromJson.MixDigest, fromRpc.MixDigest)
+	d.field("nonce", fromJson.Nonce, fromRpc.Nonce)
+	d.field("baseFeePerGas", fromJson.BaseFee, fromRpc.BaseFee)
+	d.field("withdrawalsRoot", fromJson.WithdrawalsHash, fromRpc.WithdrawalsHash)
+	return d.diffs
+}
+
+// DiffTransactions returns the fields of the rpc transaction that differ
+// from the json transaction
+func DiffTransactions(fromJson, fromRpc *types.Transaction) []FieldDiff {
+	d := differ{}
+	d.field("type", fromJson.Type(), fromRpc.Type())
+	if fromJson.Type() != fromRpc.Type() {
+		// the other fields depend on the type
+		return d.diffs
+	}
+	if fromJson.Type() != types.LegacyTxType {
+		d.field("chainId", fromJson.ChainId(), fromRpc.ChainId())
+	}
+	d.field("nonce", fromJson.Nonce(), fromRpc.Nonce())
+	if fromJson.Type() == types.DynamicFeeTxType {
+		d.field("maxPriorityFeePerGas", fromJson.GasTipCap(), fromRpc.GasTipCap())
+		d.field("maxFeePerGas", fromJson.GasFeeCap(), fromRpc.GasFeeCap())
+	} else {
+		d.field("gasPrice", fromJson.GasPrice(), fromRpc.GasPrice())
+	}
+	d.field("gas", fromJson.Gas(), fromRpc.Gas())
+	d.field("to", fromJson.To(), fromRpc.To())
+	d.field("value", fromJson.Value(), fromRpc.Value())
+	d.field("input", fromJson.Data(), fromRpc.Data())
+	d.accessList(fromJson.AccessList(), fromRpc.AccessList())
+	jsonV, jsonR, jsonS := fromJson.RawSignatureValues()
+	rpcV, rpcR, rpcS := fromRpc.RawSignatureValues()
+	d.field("v", (*hexutil.Big)(jsonV), (*hexutil.Big)(rpcV))
+	d.field("r", (*hexutil.Big)(jsonR), (*hexutil.Big)(rpcR))
+	d.field("s", (*hexutil.Big)(jsonS), (*hexutil.Big)(rpcS))
+	return d.diffs
+}
+
+// DiffReceipts returns the consensus fields of the rpc receipt that differ
+// from the json receipt
+func DiffReceipts(fromJson, fromRpc *types.Receipt) []FieldDiff {
+	d := differ{}
+	d.field("type", fromJson.Type, fromRpc.Type)
+	if len(fromJson.PostState) > 0 || len(fromRpc.PostState) > 0 {
+		d.field("root", fromJson.PostState, fromRpc.PostState)
+	} else {
+		d.field("status", fromJson.Status, fromRpc.Status)
+	}
+	d.field("cumulativeGasUsed", fromJson.CumulativeGasUsed, fromRpc.CumulativeGasUsed)
+	d.field("logsBloom", fromJson.Bloom, fromRpc.Bloom)
+	d.logs(fromJson.Logs, fromRpc.Logs)
+	return d.diffs
+}
+
+// differ collects the fields that differ
+type differ struct {
+	diffs []FieldDiff
+}
+
+func (d *differ) field(name string, fromJson, fromRpc interface{}) {
+	j, r := formatValue(fromJson), formatValue(fromRpc)
+	if j != r {
+		d.diffs = append(d.diffs, FieldDiff{Field: name, Json: j, Rpc: r})
+	}
+}
+
+func (d *differ) accessList(fromJson, fromRpc types.AccessList) {
+	d.field("accessList length", len(fromJson), len(fromRpc))
+	for i := 0; i < len(fromJson) && i < len(fromRpc); i++ {
+		name := fmt.Sprintf("accessList %d ", i)
+		d.field(name+"address", fromJson[i].Address, fromRpc[i].Address)
+		d.field(name+"storageKeys", fromJson[i].StorageKeys, fromRpc[i].StorageKeys)
+	}
+}
+
+// logs compares logs by position. A log that is found at another position
+// on the rpc side is reported as an ordering difference.
+func (d *differ) logs(fromJson, fromRpc []*types.Log) {
+	d.field("logs length", len(fromJson), len(fromRpc))
+	for j := 0; j < len(fromJson) && j < len(fromRpc); j++ {
+		if sameLog(fromJson[j], fromRpc[j]) {
+			continue
+		}
+		name := fmt.Sprintf("log %d ", j)
+		moved := -1
+		for k, log := range fromRpc {
+			if k != j && sameLog(fromJson[j], log) {
+				moved = k
+				break
+			}
+		}
+		if moved >= 0 {
+			d.diffs = append(d.diffs, FieldDiff{
+				Field: name + "order",
+				Json:  fmt.Sprintf("at index %d", j),
+				Rpc:   fmt.Sprintf("at index %d", moved),
+			})
+			continue
+		}
+		d.field(name+"address", fromJson[j].Address, fromRpc[j].Address)
+		d.field(name+"topics", fromJson[j].Topics, fromRpc[j].Topics)
+		d.field(name+"data", fromJson[j].Data, fromRpc[j].Data)
+	}
+}
+
+// sameLog reports whether two logs have the same consensus fields
+func sameLog(a, b *types.Log) bool {
+	return a.Address == b.Address &&
+		formatValue(a.Topics) == formatValue(b.Topics) &&
+		formatValue(a.Data) == formatValue(b.Data)
+}
+
+// formatValue formats a field for a diff: quantities in decimal, bytes and
+// hashes in hex, and nil pointers as missing
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "missing"
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "missing"
+	}
+	switch value := v.(type) {
+	case []byte:
+		return hexutil.Encode(value)
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(text)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = formatValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// shorten cuts a long value, such as a bloom or log data, to the part
+// around its first difference with other
+func shorten(s, other string) string {
+	const context = 24
+	if len(s) <= 2*context+26 {
+		return s
+	}
+	first := 0
+	for first < len(s) && first < len(other) && s[first] == other[first] {
+		first++
+	}
+	start, end := first-context, first+context
+	if start < 0 {
+		start, end = 0, 2*context
+	}
+	if end > len(s) {
+		start, end = len(s)-2*context, len(s)
+	}
+	short := s[start:end]
+	if start > 0 {
+		short = "..." + short
+	}
+	if end < len(s) {
+		short += "..."
+	}
+	return fmt.Sprintf("%s (%d chars, differs at %d)", short, len(s), first)
+}
@@ -0,0 +1,45 @@
+package verify_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+// shanghaiBlock is a mainnet block with withdrawals
+const shanghaiBlock = "../trie-test/data/block-18189758.json"
+
+func TestDiffHeadersWithdrawalsRoot(t *testing.T) {
+	fromJson, err := loader.HeaderFromJSON(shanghaiBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromRpc := *fromJson
+	if diffs := verify.DiffHeaders(fromJson, &fromRpc); len(diffs) != 0 {
+		t.Fatalf("diffs %v for the same header", diffs)
+	}
+
+	root := common.HexToHash("0x01")
+	fromRpc.WithdrawalsHash = &root
+	diffs := verify.DiffHeaders(fromJson, &fromRpc)
+	if len(diffs) != 1 || diffs[0].Field != "withdrawalsRoot" {
+		t.Fatalf("diffs %v, want a single withdrawalsRoot diff", diffs)
+	}
+	if diffs[0].Json != fromJson.WithdrawalsHash.Hex() || diffs[0].Rpc != root.Hex() {
+		t.Fatalf("withdrawalsRoot diff %s", diffs[0])
+	}
+	err = &verify.MismatchError{Message: "header", Diffs: diffs}
+	if strings.Contains(err.Error(), "decoded fields are equal") {
+		t.Fatalf("error %q reports equal fields", err)
+	}
+
+	fromRpc.WithdrawalsHash = nil
+	diffs = verify.DiffHeaders(fromJson, &fromRpc)
+	if len(diffs) != 1 || diffs[0].Rpc != "missing" {
+		t.Fatalf("diffs %v, want the rpc withdrawalsRoot missing", diffs)
+	}
+}
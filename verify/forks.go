@@ -0,0 +1,105 @@
+package verify
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+)
+
+// Schedule is the fork schedule of a chain: the forks of its chain config
+// activated by block number, the merge at its terminal total difficulty,
+// and the forks activated by timestamp after the merge
+type Schedule struct {
+	Config *params.ChainConfig
+}
+
+// LoadSchedule load the fork schedule of mainnet for "mainnet", or else of
+// the genesis.json file at genesis
+func LoadSchedule(genesis string) (Schedule, error) {
+	config, err := forks.ChainConfig(genesis)
+	if err != nil {
+		return Schedule{}, err
+	}
+	return Schedule{Config: config}, nil
+}
+
+// IsMerged reports whether a header comes after the merge, and follows the
+// proof-of-stake rules. With the total difficulty of its parent, the header
+// is merged when the parent reached the terminal total difficulty. Without
+// it, a header with no difficulty is taken as merged, as the beacon engine
+// of geth does.
+func (s Schedule) IsMerged(header *types.Header, parentTD *big.Int) bool {
+	ttd := s.Config.TerminalTotalDifficulty
+	if ttd == nil {
+		return false
+	}
+	if parentTD != nil {
+		return parentTD.Cmp(ttd) >= 0
+	}
+	return header.Difficulty != nil && header.Difficulty.Sign() == 0
+}
+
+// IsTerminal reports whether a block of total difficulty td, whose parent
+// has the total difficulty parentTD, is the terminal proof-of-work block:
+// the first to reach the terminal total difficulty
+func (s Schedule) IsTerminal(td, parentTD *big.Int) bool {
+	if td == nil || parentTD == nil {
+		return false
+	}
+	return s.Config.IsTerminalPoWBlock(parentTD, td)
+}
+
+// Fork returns the name of the latest fork active at a header, by block
+// number before the merge, Paris at the merge, and by timestamp after it
+func (s Schedule) Fork(header *types.Header, merged bool) string {
+	if merged {
+		switch {
+		case s.Config.IsPrague(header.Time):
+			return "Prague"
+		case s.Config.IsCancun(header.Time):
+			return "Cancun"
+		case s.Config.IsShanghai(header.Time):
+			return "Shanghai"
+		}
+		return "Paris"
+	}
+	c := s.Config
+	forks := []struct {
+		name  string
+		block *big.Int
+	}{
+		{"Gray Glacier", c.GrayGlacierBlock},
+		{"Arrow Glacier", c.ArrowGlacierBlock},
+		{"London", c.LondonBlock},
+		{"Berlin", c.BerlinBlock},
+		{"Muir Glacier", c.MuirGlacierBlock},
+		{"Istanbul", c.IstanbulBlock},
+		{"Petersburg", c.PetersburgBlock},
+		{"Constantinople", c.ConstantinopleBlock},
+		{"Byzantium", c.ByzantiumBlock},
+		{"Spurious Dragon", c.EIP158Block},
+		{"Tangerine Whistle", c.EIP150Block},
+		{"Homestead", c.HomesteadBlock},
+	}
+	for _, fork := range forks {
+		if fork.block != nil && fork.block.Cmp(header.Number) <= 0 {
+			return fork.name
+		}
+	}
+	return "Frontier"
+}
+
+// CheckSupported returns a forks.ErrUnsupported error for a header of
+// Cancun or a later fork, whose rules the go-ethereum version of this module
+// does not have
+func (s Schedule) CheckSupported(header *types.Header, merged bool) error {
+	if merged && s.Config.IsCancun(header.Time) {
+		return fmt.Errorf("%w: block %d is a %s block, go-ethereum %s checks blocks up to Shanghai",
+			forks.ErrUnsupported, header.Number, s.Fork(header, merged), params.Version)
+	}
+	return nil
+}
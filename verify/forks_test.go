@@ -0,0 +1,59 @@
+package verify_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/forks"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+func TestScheduleFork(t *testing.T) {
+	schedule, err := verify.LoadSchedule("mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		number      int64
+		time        uint64
+		merged      bool
+		fork        string
+		unsupported bool
+	}{
+		{number: 1, fork: "Frontier"},
+		{number: 1150000, fork: "Homestead"},
+		{number: 2675000, fork: "Spurious Dragon"},
+		{number: 12965000, fork: "London"},
+		{number: 15209997, fork: "Gray Glacier"},
+		{number: 15537394, time: 1663224179, merged: true, fork: "Paris"},
+		{number: 18189758, time: 1695902135, merged: true, fork: "Shanghai"},
+		{number: 19426587, time: 1710338135, merged: true, fork: "Cancun", unsupported: true},
+		{number: 22431084, time: 1746612311, merged: true, fork: "Prague", unsupported: true},
+	}
+	for _, test := range tests {
+		header := &types.Header{Number: big.NewInt(test.number), Time: test.time}
+		if fork := schedule.Fork(header, test.merged); fork != test.fork {
+			t.Errorf("block %d: fork %s, want %s", test.number, fork, test.fork)
+		}
+		err := schedule.CheckSupported(header, test.merged)
+		if test.unsupported != errors.Is(err, forks.ErrUnsupported) {
+			t.Errorf("block %d: unsupported fork error %v, want one: %t", test.number, err, test.unsupported)
+		}
+	}
+}
+
+func TestCheckHeaderRulesUnsupportedFork(t *testing.T) {
+	schedule, err := verify.LoadSchedule("mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := &types.Header{Number: big.NewInt(19426586), Time: 1710338123, Difficulty: new(big.Int)}
+	header := &types.Header{Number: big.NewInt(19426587), Time: 1710338135, Difficulty: new(big.Int)}
+	checks := verify.CheckHeaderRules(schedule, header, parent, nil)
+	if len(checks) != 1 || checks[0].Name != "fork" || !errors.Is(checks[0].Err, forks.ErrUnsupported) {
+		t.Fatalf("checks %v, want a single fork check with an unsupported fork error", checks)
+	}
+}
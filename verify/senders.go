@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// secp256k1HalfN is the largest s of a signature that is not malleable
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// ForkSigner returns the signer for the transactions of a block, with the
+// name of the fork that introduced it: Frontier, Homestead, which rejects
+// high s values, EIP-155, which adds the chain ID to v, Berlin, which adds
+// access list transactions, and London, which adds dynamic fee transactions
+func ForkSigner(config *params.ChainConfig, number *big.Int) (types.Signer, string) {
+	switch {
+	case config.IsLondon(number):
+		return types.NewLondonSigner(config.ChainID), "London"
+	case config.IsBerlin(number):
+		return types.NewEIP2930Signer(config.ChainID), "Berlin"
+	case config.IsEIP155(number):
+		return types.NewEIP155Signer(config.ChainID), "EIP-155"
+	case config.IsHomestead(number):
+		return types.HomesteadSigner{}, "Homestead"
+	}
+	return types.FrontierSigner{}, "Frontier"
+}
+
+// SenderResult is the outcome of recovering the senders of a block
+type SenderResult struct {
+	Fork  string // fork of the signer used
+	HighS []int  // transactions with a malleable signature, s above secp256k1n/2
+}
+
+// CheckSenders recover the sender of each transaction of a block with the
+// signer of its fork and compare it with the "from" address of the json
+// transaction. High s values are flagged, and are an error from Homestead on.
+func CheckSenders(config *params.ChainConfig, number *big.Int, txs []*types.Transaction, senders []common.Address) (SenderResult, error) {
+	signer, fork := ForkSigner(config, number)
+	result := SenderResult{Fork: fork}
+	if len(senders) != len(txs) {
+		return result, fmt.Errorf("%d senders from json for %d transactions", len(senders), len(txs))
+	}
+
+	var problems []string
+	for i, tx := range txs {
+		prefix := fmt.Sprintf("transaction %d (%s)", i, tx.Hash())
+		_, _, s := tx.RawSignatureValues()
+		highS := s.Cmp(secp256k1HalfN) > 0
+		if highS {
+			result.HighS = append(result.HighS, i)
+		}
+		sender, err := types.Sender(signer, tx)
+		switch {
+		case err != nil && highS && fork != "Frontier":
+			problems = append(problems, fmt.Sprintf("%s: high s %#x, invalid since Homestead", prefix, s))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: no sender with the %s signer: %v", prefix, fork, err))
+		case sender != senders[i]:
+			problems = append(problems, fmt.Sprintf("%s: recovered sender %s, from %s", prefix, sender, senders[i]))
+		}
+	}
+	if len(problems) > 0 {
+		return result, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package verify_test
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+// testKey signs the transactions of the sender tests
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// signTx signs a transaction with the signer of the fork at number
+func signTx(t *testing.T, number *big.Int, inner types.TxData) *types.Transaction {
+	t.Helper()
+	signer, _ := verify.ForkSigner(params.MainnetChainConfig, number)
+	tx, err := types.SignNewTx(testKey, signer, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// withHighS returns the transaction with the other valid signature of a
+// pre-EIP-155 tx: s replaced by secp256k1n - s, and the recovery id of v
+// flipped
+func withHighS(tx *types.Transaction) *types.Transaction {
+	v, r, s := tx.RawSignatureValues()
+	highS := new(big.Int).Sub(crypto.S256().Params().N, s)
+	flipped := new(big.Int).Sub(big.NewInt(27+28), v)
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    tx.Nonce(),
+		GasPrice: tx.GasPrice(),
+		Gas:      tx.Gas(),
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+		V:        flipped,
+		R:        r,
+		S:        highS,
+	})
+}
+
+func TestCheckSenders(t *testing.T) {
+	config := params.MainnetChainConfig
+	sender := crypto.PubkeyToAddress(testKey.PublicKey)
+	to := common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	legacy := func(nonce uint64) *types.LegacyTx {
+		return &types.LegacyTx{Nonce: nonce, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)}
+	}
+
+	frontier := big.NewInt(46147) // first block with a transaction
+	homestead := config.HomesteadBlock
+	eip155 := config.EIP155Block
+	london := config.LondonBlock
+	frontierTx := signTx(t, frontier, legacy(0))
+	homesteadTx := signTx(t, homestead, legacy(1))
+
+	tests := []struct {
+		name    string
+		number  *big.Int
+		txs     []*types.Transaction
+		senders []common.Address
+		fork    string
+		highS   []int
+		err     string // part of the error, no error when empty
+	}{
+		{
+			name:    "frontier",
+			number:  frontier,
+			txs:     []*types.Transaction{frontierTx},
+			senders: []common.Address{sender},
+			fork:    "Frontier",
+		},
+		{
+			name:    "frontier high s is flagged only",
+			number:  frontier,
+			txs:     []*types.Transaction{frontierTx, withHighS(frontierTx)},
+			senders: []common.Address{sender, sender},
+			fork:    "Frontier",
+			highS:   []int{1},
+		},
+		{
+			name:    "homestead",
+			number:  homestead,
+			txs:     []*types.Transaction{homesteadTx},
+			senders: []common.Address{sender},
+			fork:    "Homestead",
+		},
+		{
+			name:    "homestead high s is invalid",
+			number:  homestead,
+			txs:     []*types.Transaction{withHighS(homesteadTx)},
+			senders: []common.Address{sender},
+			fork:    "Homestead",
+			highS:   []int{0},
+			err:     "transaction 0 (" + withHighS(homesteadTx).Hash().Hex() + "): high s",
+		},
+		{
+			name:    "eip-155",
+			number:  eip155,
+			txs:     []*types.Transaction{signTx(t, eip155, legacy(2))},
+			senders: []common.Address{sender},
+			fork:    "EIP-155",
+		},
+		{
+			name:   "london dynamic fee",
+			number: london,
+			txs: []*types.Transaction{signTx(t, london, &types.DynamicFeeTx{
+				ChainID: config.ChainID, Nonce: 3, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(2e9), Gas: 21000, To: &to, Value: big.NewInt(1),
+			})},
+			senders: []common.Address{sender},
+			fork:    "London",
+		},
+		{
+			name:    "pre-eip-155 transaction after eip-155",
+			number:  london,
+			txs:     []*types.Transaction{homesteadTx},
+			senders: []common.Address{sender},
+			fork:    "London",
+		},
+		{
+			name:    "wrong from",
+			number:  homestead,
+			txs:     []*types.Transaction{homesteadTx},
+			senders: []common.Address{to},
+			fork:    "Homestead",
+			err:     "recovered sender " + sender.Hex() + ", from " + to.Hex(),
+		},
+		{
+			name:    "eip-155 transaction before eip-155",
+			number:  homestead,
+			txs:     []*types.Transaction{signTx(t, eip155, legacy(4))},
+			senders: []common.Address{sender},
+			fork:    "Homestead",
+			err:     "no sender with the Homestead signer",
+		},
+		{
+			name:   "missing senders",
+			number: homestead,
+			txs:    []*types.Transaction{homesteadTx},
+			fork:   "Homestead",
+			err:    "0 senders from json for 1 transactions",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			result, err := verify.CheckSenders(config, test.number, test.txs, test.senders)
+			if result.Fork != test.fork {
+				t.Errorf("fork %s, want %s", result.Fork, test.fork)
+			}
+			if fmt.Sprint(result.HighS) != fmt.Sprint(test.highS) {
+				t.Errorf("high s transactions %v, want %v", result.HighS, test.highS)
+			}
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Errorf("expected an error with %q", test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Errorf("error %q, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+)
+
+// CheckTransactionHashes check that each transaction hashes to the hash its
+// json claims, as keccak of its canonical encoding, which for typed
+// transactions includes the EIP-2718 type byte, and that its
+// transactionIndex gives the key rlp(index) it has in the transactions
+// trie. The receipts, when given, must have the transactionHash of the
+// transaction at their index.
+func CheckTransactionHashes(txs []*types.Transaction, refs []loader.TxRef, receipts []*types.Receipt) error {
+	if len(refs) != len(txs) {
+		return fmt.Errorf("%d hashes from json for %d transactions", len(refs), len(txs))
+	}
+	if receipts != nil && len(receipts) != len(txs) {
+		return fmt.Errorf("%d receipts for %d transactions", len(receipts), len(txs))
+	}
+
+	var problems []string
+	for i, tx := range txs {
+		prefix := fmt.Sprintf("transaction %d", i)
+		encoded, err := tx.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		hash := crypto.Keccak256Hash(encoded)
+		if hash != refs[i].Hash {
+			problems = append(problems, fmt.Sprintf("%s: encoding hashes to %s, json hash %s", prefix, hash, refs[i].Hash))
+		}
+		key := rlp.AppendUint64(nil, uint64(i))
+		if claimed := rlp.AppendUint64(nil, refs[i].Index); !bytes.Equal(claimed, key) {
+			problems = append(problems, fmt.Sprintf("%s: trie key %s, transactionIndex %d has key %s",
+				prefix, hexutil.Encode(key), refs[i].Index, hexutil.Encode(claimed)))
+		}
+		if receipts != nil && receipts[i].TxHash != hash {
+			problems = append(problems, fmt.Sprintf("receipt %d: transactionHash %s, transaction hash %s", i, receipts[i].TxHash, hash))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
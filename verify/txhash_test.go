@@ -0,0 +1,59 @@
+package verify_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+func TestCheckTransactionHashes(t *testing.T) {
+	const index = 3 // transaction changed by the test cases
+	tests := []struct {
+		name   string
+		change func(b *fixtureBlock, refs []loader.TxRef)
+		err    string // part of the error, no error when empty
+	}{
+		{name: "fixture block"},
+		{
+			name: "swapped transactions",
+			change: func(b *fixtureBlock, refs []loader.TxRef) {
+				b.txs[index], b.txs[index+1] = b.txs[index+1], b.txs[index]
+			},
+			err: "transaction 3: encoding hashes to",
+		},
+		{
+			name:   "wrong transactionIndex",
+			change: func(b *fixtureBlock, refs []loader.TxRef) { refs[index].Index = index + 1 },
+			err:    "transaction 3: trie key 0x03, transactionIndex 4 has key 0x04",
+		},
+		{
+			name:   "receipt with the hash of another transaction",
+			change: func(b *fixtureBlock, refs []loader.TxRef) { b.json[index].TxHash = common.HexToHash("0x01") },
+			err:    "receipt 3: transactionHash 0x0000000000000000000000000000000000000000000000000000000000000001",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b := loadFixtureBlock(t)
+			refs, err := loader.BlockTxRefsFromJSON(loader.FixturePath(dataDir, blockNum, "transactions"), loader.NumberID(blockNum))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.change != nil {
+				test.change(&b, refs)
+			}
+			err = verify.CheckTransactionHashes(b.txs, refs, b.json)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("error %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
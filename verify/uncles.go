@@ -0,0 +1,148 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// MaxUncles is the most uncles a block can include
+const MaxUncles = 2
+
+// VerifyUncles fetch the uncles of an rlp-encoded block by index, and its
+// ancestors, from the client and check the uncles against them. Blocks
+// without uncles are only checked against the uncles hash in their header.
+func VerifyUncles(ctx context.Context, client *rpc.Client, blockBytes []byte) ([]Check, error) {
+	block, err := loader.SplitRawBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	header, err := loader.BytesToHeader(block.Header)
+	if err != nil {
+		return nil, err
+	}
+	uncles, err := block.UncleHeaders()
+	if err != nil {
+		return nil, err
+	}
+	data := new(rpc.UncleData)
+	if len(uncles) > 0 {
+		if data, err = client.FetchUncleData(ctx, header.Number.Uint64(), len(uncles)); err != nil {
+			return nil, err
+		}
+	}
+	return CheckUncles(block, data), nil
+}
+
+// CheckUncles check the uncles of a block: that they hash to the sha3Uncles
+// of its header, that they are the uncles the client returns by index, and
+// that they follow the ommer rules
+func CheckUncles(block *loader.RawBlock, data *rpc.UncleData) []Check {
+	hashCheck := Check{Name: "uncles hash"}
+	rulesCheck := Check{Name: "ommer rules"}
+	header, err := loader.BytesToHeader(block.Header)
+	if err != nil {
+		hashCheck.Err, rulesCheck.Err = err, err
+		return []Check{hashCheck, rulesCheck}
+	}
+	uncles, err := block.UncleHeaders()
+	if err != nil {
+		hashCheck.Err, rulesCheck.Err = err, err
+		return []Check{hashCheck, rulesCheck}
+	}
+
+	if hash := types.CalcUncleHash(uncles); hash != header.UncleHash {
+		hashCheck.Err = fmt.Errorf("uncles hash does not match: header %s, %d uncles in body %s",
+			header.UncleHash, len(uncles), hash)
+	} else {
+		hashCheck.Err = CheckUncleHashes(uncles, data.Uncles, data.UncleHashes)
+	}
+	rulesCheck.Err = CheckUncleRules(header, uncles, data.Ancestors, data.AncestorUncles)
+	return []Check{hashCheck, rulesCheck}
+}
+
+// CheckUncleHashes check the uncle headers of a raw block against the json
+// uncles from eth_getUncleByBlockNumberAndIndex and the hashes reported with
+// them
+func CheckUncleHashes(uncles []*types.Header, unclesFromJson []*types.Header, hashesFromJson []common.Hash) error {
+	if len(unclesFromJson) != len(uncles) {
+		return fmt.Errorf("%d uncles in block, %d uncles from json", len(uncles), len(unclesFromJson))
+	}
+	for i, uncle := range uncles {
+		hash := uncle.Hash()
+		if hash != hashesFromJson[i] {
+			return fmt.Errorf("uncle %d hashes to %s, json uncle has hash %s", i, hash, hashesFromJson[i])
+		}
+		if jsonHash := unclesFromJson[i].Hash(); jsonHash != hash {
+			return &MismatchError{
+				Message: fmt.Sprintf("uncle %d from json does not match uncle from rpc", i),
+				Diffs:   DiffHeaders(unclesFromJson[i], uncle),
+			}
+		}
+	}
+	return nil
+}
+
+// CheckUncleRules check the uncles of a block against the ommer rules: at
+// most MaxUncles uncles, each one distinct, not an ancestor and not included
+// before, branching off one of the ancestors at a depth of at most
+// rpc.UncleWindow-1. ancestors are the headers before the block, parent
+// first, and ancestorUncles the uncles they included.
+func CheckUncleRules(header *types.Header, uncles []*types.Header, ancestors []*types.Header, ancestorUncles []common.Hash) error {
+	if len(uncles) == 0 {
+		return nil
+	}
+	var problems []string
+	if len(uncles) > MaxUncles {
+		problems = append(problems, fmt.Sprintf("%d uncles, at most %d are allowed", len(uncles), MaxUncles))
+	}
+
+	ancestorHashes := make(map[common.Hash]bool, len(ancestors)+1)
+	ancestorHashes[header.Hash()] = true
+	for _, ancestor := range ancestors {
+		ancestorHashes[ancestor.Hash()] = true
+	}
+	included := make(map[common.Hash]bool, len(ancestorUncles))
+	for _, hash := range ancestorUncles {
+		included[hash] = true
+	}
+
+	seen := make(map[common.Hash]int, len(uncles))
+	number := header.Number.Uint64()
+	for i, uncle := range uncles {
+		hash := uncle.Hash()
+		prefix := fmt.Sprintf("uncle %d (#%d %s)", i, uncle.Number.Uint64(), hash)
+		if first, ok := seen[hash]; ok {
+			problems = append(problems, fmt.Sprintf("%s: duplicate of uncle %d", prefix, first))
+			continue
+		}
+		seen[hash] = i
+		if included[hash] {
+			problems = append(problems, prefix+": already included by an ancestor")
+		}
+		if ancestorHashes[hash] {
+			problems = append(problems, prefix+": is an ancestor")
+			continue
+		}
+		uncleNumber := uncle.Number.Uint64()
+		if uncleNumber >= number || number-uncleNumber >= rpc.UncleWindow {
+			problems = append(problems, fmt.Sprintf("%s: depth %d, expected 1 to %d",
+				prefix, int64(number)-int64(uncleNumber), rpc.UncleWindow-1))
+			continue
+		}
+		// without ancestors only the depth of the uncle can be checked
+		if len(ancestors) > 0 && (!ancestorHashes[uncle.ParentHash] || uncle.ParentHash == header.ParentHash) {
+			problems = append(problems, prefix+": parent is not an ancestor of the block")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
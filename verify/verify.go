@@ -0,0 +1,285 @@
+// Package verify checks data from an Ethereum client, or loaded from json
+// fixtures, against the hashes committed to in the block header.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/KohdMonkey/validate-ethereum-data/hashing"
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+)
+
+// VerifyRawHeader verify rlp-encoded header data from the client against the
+// header loaded from json, and return the hash of the header from the client
+func VerifyRawHeader(ctx context.Context, client *rpc.Client, blockNum uint64, headerFromJson *types.Header, expectedHash common.Hash) (common.Hash, error) {
+	// fetch rlp-encoded header
+	headerBytes, err := client.GetHeaderRlp(ctx, blockNum)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return CheckRawHeader(headerBytes, headerFromJson, expectedHash)
+}
+
+// CheckRawHeader check an rlp-encoded header against the header loaded from
+// json, and return its hash
+func CheckRawHeader(headerBytes []byte, headerFromJson *types.Header, expectedHash common.Hash) (common.Hash, error) {
+	// check if rlp-encoded bytes of the header from json match
+	headerBytesFromJson, err := rlp.EncodeToBytes(headerFromJson)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// construct header from raw bytes and calculate the hash
+	header, err := loader.BytesToHeader(headerBytes)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !bytes.Equal(headerBytesFromJson, headerBytes) {
+		return common.Hash{}, &MismatchError{
+			Message: "raw header from json does not match raw header from rpc",
+			Diffs:   DiffHeaders(headerFromJson, header),
+		}
+	}
+	headerHash := header.Hash()
+	if headerHash != expectedHash {
+		return headerHash, fmt.Errorf("header hash does not match")
+	}
+	return headerHash, nil
+}
+
+// VerifyRawBlock verify rlp-encoded block data from the client, and return
+// the hash of the block from the client and the checks of its body
+func VerifyRawBlock(ctx context.Context, client *rpc.Client, blockNum uint64, expectedHash common.Hash) (common.Hash, []BodyCheck, error) {
+	// fetch rlp-encoded block
+	blockBytes, err := client.GetBlockRlp(ctx, blockNum)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return CheckRawBlock(blockBytes, expectedHash)
+}
+
+// CheckRawBlock check an rlp-encoded block against the expected hash and
+// return its hash. The hash only covers the header, so the body is checked
+// against the roots in the header, with one result per root.
+func CheckRawBlock(blockBytes []byte, expectedHash common.Hash) (common.Hash, []BodyCheck, error) {
+	block, err := loader.SplitRawBlock(blockBytes)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	checks := CheckBlockBody(block)
+	blockHash := block.Hash()
+	if blockHash != expectedHash {
+		return blockHash, checks, fmt.Errorf("block hash does not match")
+	}
+	return blockHash, checks, nil
+}
+
+// Check is the result of one named check of a block
+type Check struct {
+	Name string
+	Err  error
+}
+
+// BodyCheck is the result of checking one root in a block header against the
+// root calculated from the block body
+type BodyCheck struct {
+	Name     string      // json name of the header field
+	Header   common.Hash // root in the header
+	Computed common.Hash // root calculated from the body
+	Err      error
+}
+
+// CheckBlockBody check the body of a block against the roots in its header,
+// with one result per root
+func CheckBlockBody(block *loader.RawBlock) []BodyCheck {
+	txCheck := BodyCheck{Name: "transactionsRoot"}
+	txCheck.Header, txCheck.Err = block.TxHash()
+	if txCheck.Err == nil {
+		txCheck.Computed = hashing.StackTrieRoot(hashing.EncodedList(block.Transactions))
+		if txCheck.Computed != txCheck.Header {
+			txCheck.Err = fmt.Errorf("transactions root does not match: header %s, %d transactions in body %s",
+				txCheck.Header, len(block.Transactions), txCheck.Computed)
+		}
+	}
+
+	uncleCheck := BodyCheck{Name: "sha3Uncles"}
+	uncleCheck.Header, uncleCheck.Err = block.UncleHash()
+	if uncleCheck.Err == nil {
+		uncleCheck.Computed = crypto.Keccak256Hash(block.Uncles)
+		if uncleCheck.Computed != uncleCheck.Header {
+			uncleCheck.Err = fmt.Errorf("uncles hash does not match: header %s, body %s",
+				uncleCheck.Header, uncleCheck.Computed)
+		}
+	}
+	checks := []BodyCheck{txCheck, uncleCheck}
+
+	withdrawalsRoot, err := block.WithdrawalsHash()
+	if err != nil || withdrawalsRoot != nil || block.HasWithdrawals {
+		checks = append(checks, checkWithdrawals(block, withdrawalsRoot, err))
+	}
+	return checks
+}
+
+// checkWithdrawals check the withdrawals of a body against the header, which
+// must both have them or both not
+func checkWithdrawals(block *loader.RawBlock, withdrawalsRoot *common.Hash, err error) BodyCheck {
+	check := BodyCheck{Name: "withdrawalsRoot", Err: err}
+	switch {
+	case err != nil:
+	case withdrawalsRoot == nil:
+		check.Err = fmt.Errorf("body has %d withdrawals, header has no withdrawals root", len(block.Withdrawals))
+	case !block.HasWithdrawals:
+		check.Header = *withdrawalsRoot
+		check.Err = fmt.Errorf("header has withdrawals root %s, body has no withdrawals", check.Header)
+	default:
+		check.Header = *withdrawalsRoot
+		check.Computed, check.Err = VerifyWithdrawalsRoot(block.Withdrawals, check.Header)
+		if check.Err != nil {
+			check.Err = fmt.Errorf("%w: header %s, %d withdrawals in body %s",
+				check.Err, check.Header, len(block.Withdrawals), check.Computed)
+		}
+	}
+	return check
+}
+
+// VerifyRawReceipts verify rlp-encoded receipts from the client against the
+// receipts loaded from json, and return the receipts root from the client.
+// The comparison with json is skipped when receiptsFromJson is nil.
+func VerifyRawReceipts(ctx context.Context, client *rpc.Client, blockNum uint64, receiptsFromJson []*types.Receipt, expectedRoot common.Hash) (common.Hash, error) {
+	// fetch rlp-encoded receipts and parse them into receipts
+	receiptsBytesArr, err := client.GetRawReceipts(ctx, blockNum)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return CheckRawReceipts(receiptsBytesArr, receiptsFromJson, expectedRoot)
+}
+
+// CheckRawReceipts check binary-encoded receipts against the receipts loaded
+// from json, and return their root. The comparison with json is skipped when
+// receiptsFromJson is nil.
+func CheckRawReceipts(receiptsBytesArr [][]byte, receiptsFromJson []*types.Receipt, expectedRoot common.Hash) (common.Hash, error) {
+	receipts, err := loader.BytesToReceipts(receiptsBytesArr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// check if receipts from json match
+	if receiptsFromJson == nil {
+		return VerifyReceiptsRoot(receipts, expectedRoot)
+	}
+	if len(receiptsFromJson) != len(receipts) {
+		return common.Hash{}, fmt.Errorf("%d receipts from json, %d receipts from rpc",
+			len(receiptsFromJson), len(receipts))
+	}
+	var mismatched []string
+	var diffs []FieldDiff
+	for i := range receipts {
+		receiptBinaryFromJson, err := receiptsFromJson[i].MarshalBinary()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if !bytes.Equal(receiptsBytesArr[i], receiptBinaryFromJson) {
+			mismatched = append(mismatched, fmt.Sprint(i))
+			for _, diff := range DiffReceipts(receiptsFromJson[i], receipts[i]) {
+				diff.Field = fmt.Sprintf("receipt %d %s", i, diff.Field)
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+	if len(mismatched) == 1 {
+		return common.Hash{}, &MismatchError{
+			Message: fmt.Sprintf("receipt %s from json does not match receipt from rpc", mismatched[0]),
+			Diffs:   diffs,
+		}
+	}
+	if len(mismatched) > 1 {
+		return common.Hash{}, &MismatchError{
+			Message: fmt.Sprintf("receipts %s from json do not match receipts from rpc", strings.Join(mismatched, ", ")),
+			Diffs:   diffs,
+		}
+	}
+
+	// construct trie using receipts and retrieve receipt root hash
+	return VerifyReceiptsRoot(receipts, expectedRoot)
+}
+
+// VerifyRawTransaction verify a binary-encoded transaction from the client
+// against the transaction loaded from json, and return its hash. The
+// comparison with json is skipped when txFromJson is nil.
+func VerifyRawTransaction(ctx context.Context, client *rpc.Client, txHash common.Hash, txFromJson *types.Transaction) (common.Hash, error) {
+	txBytes, err := client.GetRawTransaction(ctx, txHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return CheckRawTransaction(txBytes, txFromJson, txHash)
+}
+
+// CheckRawTransaction check a binary-encoded transaction against the
+// transaction loaded from json and the expected hash, and return its hash.
+// The comparison with json is skipped when txFromJson is nil.
+func CheckRawTransaction(txBytes []byte, txFromJson *types.Transaction, expectedHash common.Hash) (common.Hash, error) {
+	// decode the transaction from raw bytes and calculate the hash
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(txBytes); err != nil {
+		return common.Hash{}, err
+	}
+	if txFromJson != nil {
+		txBytesFromJson, err := txFromJson.MarshalBinary()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if !bytes.Equal(txBytesFromJson, txBytes) {
+			return common.Hash{}, &MismatchError{
+				Message: "raw transaction from json does not match raw transaction from rpc",
+				Diffs:   DiffTransactions(txFromJson, tx),
+			}
+		}
+	}
+	txHash := tx.Hash()
+	if txHash != expectedHash {
+		return txHash, fmt.Errorf("transaction hash does not match")
+	}
+	return txHash, nil
+}
+
+// VerifyTransactionsRoot calculate the transactions root with a StackTrie,
+// check it against the expected root and return it
+func VerifyTransactionsRoot(txs []*types.Transaction, expectedRoot common.Hash) (common.Hash, error) {
+	root := hashing.StackTrieRoot(types.Transactions(txs))
+	if root != expectedRoot {
+		return root, fmt.Errorf("transactions root does not match")
+	}
+	return root, nil
+}
+
+// VerifyReceiptsRoot calculate the receipts root with a StackTrie, check it
+// against the expected root and return it
+func VerifyReceiptsRoot(receipts []*types.Receipt, expectedRoot common.Hash) (common.Hash, error) {
+	root := hashing.StackTrieRoot(types.Receipts(receipts))
+	if root != expectedRoot {
+		return root, fmt.Errorf("receipts root does not match")
+	}
+	return root, nil
+}
+
+// VerifyWithdrawalsRoot calculate the withdrawals root with both StackTrie
+// and simpletrie, check them against the expected root and return it
+func VerifyWithdrawalsRoot(withdrawals types.Withdrawals, expectedRoot common.Hash) (common.Hash, error) {
+	root := hashing.StackTrieRoot(withdrawals)
+	if root != expectedRoot {
+		return root, fmt.Errorf("withdrawals root does not match")
+	}
+	if simpleRoot := hashing.SimpleTrieRoot(withdrawals); simpleRoot != root {
+		return simpleRoot, fmt.Errorf("withdrawals root from simpletrie does not match")
+	}
+	return root, nil
+}
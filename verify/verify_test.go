@@ -0,0 +1,518 @@
+package verify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KohdMonkey/validate-ethereum-data/loader"
+	"github.com/KohdMonkey/validate-ethereum-data/mockgeth"
+	"github.com/KohdMonkey/validate-ethereum-data/rpc"
+	"github.com/KohdMonkey/validate-ethereum-data/verify"
+)
+
+const (
+	dataDir      = "../raw-data/data"
+	hiveDir      = "../raw-data/data/hive"
+	blockNum     = 15209997
+	receiptsRoot = "0x5ff308f613dd6b9cc880622fe638c4099c38fc85d02db7c738952618380360fd"
+	headerHash   = "0x868248867378bf14da3923ba2242e00a97154f390956ee5d5f7793f97920c047"
+
+	// hiveUncle is the uncle of block 6 of the hive chain
+	hiveUncle = "0x97186bc5df663e72934212ab5a7b4449f07f12f44b267e119817791fe0ed66c5"
+)
+
+// scenario is a set of faults and the outcome the Verify* functions should
+// have with them
+type scenario struct {
+	name   string
+	faults []mockgeth.Fault
+	// unknown verifies the block after the fixture, which the server lacks
+	unknown bool
+	// legacy serves the raw methods under their pre-1.11 names
+	legacy bool
+	// hive runs the checks over the blocks of the hive test chain instead
+	// of the fixture block
+	hive bool
+	// config changes the client config, timeout bounds the whole scenario
+	config  func(*rpc.Config)
+	timeout time.Duration
+	// check returns an error when the results are not as expected
+	check func(results map[string]error) error
+}
+
+// expected holds the data a block is verified against
+type expected struct {
+	number       uint64
+	header       *types.Header
+	receipts     []*types.Receipt
+	hash         common.Hash
+	receiptsRoot common.Hash
+	tx           *types.Transaction // a transaction of the block
+	uncles       bool
+}
+
+// runVerify runs every Verify* function against the client and returns
+// their errors by name
+func runVerify(ctx context.Context, client *rpc.Client, exp expected) map[string]error {
+	results := make(map[string]error)
+	_, results["VerifyRawHeader"] = verify.VerifyRawHeader(ctx, client, exp.number, exp.header, exp.hash)
+	_, bodyChecks, err := verify.VerifyRawBlock(ctx, client, exp.number, exp.hash)
+	results["VerifyRawBlock"] = err
+	for _, check := range bodyChecks {
+		results["VerifyRawBlock "+check.Name] = check.Err
+	}
+	_, results["VerifyRawReceipts"] = verify.VerifyRawReceipts(ctx, client, exp.number, exp.receipts, exp.receiptsRoot)
+	_, results["VerifyLogsBloom"] = verify.VerifyLogsBloom(ctx, client, exp.number, exp.receipts, exp.header.Bloom)
+	results["VerifyReceiptConsistency"] = verify.VerifyReceiptConsistency(ctx, client, exp.number, exp.header, exp.hash, exp.receipts)
+	_, results["FetchBlockData"] = client.FetchBlockData(ctx, exp.number)
+	if exp.tx != nil {
+		_, results["VerifyRawTransaction"] = verify.VerifyRawTransaction(ctx, client, exp.tx.Hash(), exp.tx)
+	}
+	return results
+}
+
+// runHiveVerify runs the checks of the hive chain blocks against the client
+// and returns the first error of each check by name
+func runHiveVerify(ctx context.Context, client *rpc.Client, blocks []expected) map[string]error {
+	results := make(map[string]error)
+	record := func(name string, err error) {
+		if _, ok := results[name]; !ok || results[name] == nil {
+			results[name] = err
+		}
+	}
+	for _, exp := range blocks {
+		// the raw data on disk was saved from a node, apart from the json
+		_, err := verify.VerifyRawHeader(ctx, client, exp.number, exp.header, exp.hash)
+		record("VerifyRawHeader", err)
+		_, bodyChecks, err := verify.VerifyRawBlock(ctx, client, exp.number, exp.hash)
+		record("VerifyRawBlock", err)
+		for _, check := range bodyChecks {
+			record("VerifyRawBlock "+check.Name, check.Err)
+		}
+		_, err = verify.VerifyRawReceipts(ctx, client, exp.number, exp.receipts, exp.receiptsRoot)
+		record("VerifyRawReceipts", err)
+		_, err = verify.VerifyLogsBloom(ctx, client, exp.number, exp.receipts, exp.header.Bloom)
+		record("VerifyLogsBloom", err)
+		record("VerifyReceiptConsistency", verify.VerifyReceiptConsistency(ctx, client, exp.number, exp.header, exp.hash, exp.receipts))
+		if !exp.uncles {
+			continue
+		}
+		blockBytes, err := client.GetBlockRlp(ctx, exp.number)
+		if err != nil {
+			record("GetBlockRlp", err)
+			continue
+		}
+		checks, err := verify.VerifyUncles(ctx, client, blockBytes)
+		record("VerifyUncles", err)
+		for _, check := range checks {
+			record(check.Name, check.Err)
+		}
+	}
+	return results
+}
+
+// allPass expects every call to succeed
+func allPass(results map[string]error) error {
+	for name, err := range results {
+		if err != nil {
+			return fmt.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// allFail expects every call to fail with an error that matches match
+func allFail(match func(error) bool) func(map[string]error) error {
+	return func(results map[string]error) error {
+		for name, err := range results {
+			if err == nil {
+				return fmt.Errorf("%s: expected an error", name)
+			}
+			if match != nil && !match(err) {
+				return fmt.Errorf("%s: unexpected error: %v", name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// failOnly expects the named calls to fail and the others to pass
+func failOnly(names ...string) func(map[string]error) error {
+	return func(results map[string]error) error {
+		failing := make(map[string]bool)
+		for _, name := range names {
+			failing[name] = true
+		}
+		for name, err := range results {
+			if failing[name] && err == nil {
+				return fmt.Errorf("%s: expected an error", name)
+			}
+			if !failing[name] && err != nil {
+				return fmt.Errorf("%s: unexpected error: %v", name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// failWithDiff expects the named call to fail with a field-level diff that
+// names field, and the others to pass
+func failWithDiff(name, field string) func(map[string]error) error {
+	return func(results map[string]error) error {
+		var mismatch *verify.MismatchError
+		if !errors.As(results[name], &mismatch) {
+			return fmt.Errorf("%s: expected a mismatch, got %v", name, results[name])
+		}
+		found := false
+		for _, diff := range mismatch.Diffs {
+			found = found || diff.Field == field
+		}
+		if !found {
+			return fmt.Errorf("%s: expected a diff of %s, got %v", name, field, mismatch)
+		}
+		return failOnly(name)(results)
+	}
+}
+
+// failWithBloom expects the bloom of a single receipt to be wrong, and both
+// the bloom check and the raw receipts diff to name that receipt
+func failWithBloom(results map[string]error) error {
+	var bloomErr *verify.BloomError
+	if !errors.As(results["VerifyLogsBloom"], &bloomErr) {
+		return fmt.Errorf("VerifyLogsBloom: expected a bloom error, got %v", results["VerifyLogsBloom"])
+	}
+	receipts := bloomErr.Receipts()
+	if len(receipts) != 1 || len(bloomErr.Diffs) != 1 {
+		return fmt.Errorf("VerifyLogsBloom: expected the bloom of one receipt to be wrong, got %v", bloomErr)
+	}
+	field := fmt.Sprintf("receipt %d logsBloom", receipts[0])
+	var mismatch *verify.MismatchError
+	if !errors.As(results["VerifyRawReceipts"], &mismatch) || len(mismatch.Diffs) != 1 || mismatch.Diffs[0].Field != field {
+		return fmt.Errorf("VerifyRawReceipts: expected a diff of %s, got %v", field, results["VerifyRawReceipts"])
+	}
+	return failOnly("VerifyLogsBloom", "VerifyRawReceipts")(results)
+}
+
+// errorIs matches errors that are one of targets
+func errorIs(targets ...error) func(error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var scenarios = []scenario{
+	{name: "no faults", check: allPass},
+	{
+		name:   "wrong header bytes",
+		faults: []mockgeth.Fault{{Method: "debug_getRawHeader", WrongBytes: true}},
+		check:  failWithDiff("VerifyRawHeader", "baseFeePerGas"),
+	},
+	{
+		name:   "wrong block bytes",
+		faults: []mockgeth.Fault{{Method: "debug_getRawBlock", WrongBytes: true}},
+		check:  failOnly("VerifyRawBlock", "VerifyReceiptConsistency"),
+	},
+	{
+		name:   "block body missing a transaction",
+		faults: []mockgeth.Fault{{Method: "debug_getRawBlock", DropTransaction: true}},
+		check:  failOnly("VerifyRawBlock transactionsRoot", "VerifyReceiptConsistency"),
+	},
+	{
+		name:   "wrong receipt bytes",
+		faults: []mockgeth.Fault{{Method: "debug_getRawReceipts", WrongBytes: true}},
+		check:  failOnly("VerifyRawReceipts", "VerifyLogsBloom", "VerifyReceiptConsistency"),
+	},
+	{
+		name:   "receipt bloom does not match its logs",
+		faults: []mockgeth.Fault{{Method: "debug_getRawReceipts", WrongBloom: true}},
+		check:  failWithBloom,
+	},
+	{
+		name:   "wrong transaction bytes",
+		faults: []mockgeth.Fault{{Method: "debug_getRawTransaction", WrongBytes: true}},
+		check:  failWithDiff("VerifyRawTransaction", "s"),
+	},
+	{
+		name:   "legacy method names",
+		legacy: true,
+		check:  allPass,
+	},
+	{
+		name:   "wrong header bytes from legacy method",
+		legacy: true,
+		faults: []mockgeth.Fault{{Method: "debug_getHeaderRlp", WrongBytes: true}},
+		check:  failOnly("VerifyRawHeader"),
+	},
+	{
+		name:   "truncated hex",
+		faults: []mockgeth.Fault{{TruncateHex: true}},
+		check:  allFail(nil),
+	},
+	{
+		name:   "debug namespace disabled",
+		faults: []mockgeth.Fault{{Method: "debug_getRawHeader", Error: &rpc.Error{Code: -32601, Message: "the method debug_getRawHeader does not exist/is not available"}}},
+		check: func(results map[string]error) error {
+			if !errors.Is(results["VerifyRawHeader"], rpc.ErrMethodNotFound) {
+				return fmt.Errorf("VerifyRawHeader: expected method not found, got %v", results["VerifyRawHeader"])
+			}
+			return failOnly("VerifyRawHeader", "FetchBlockData")(results)
+		},
+	},
+	{
+		name:   "pruned history",
+		faults: []mockgeth.Fault{{Error: &rpc.Error{Code: 4444, Message: "pruned history unavailable"}}},
+		check:  allFail(errorIs(rpc.ErrPruned)),
+	},
+	{
+		name:    "unknown block",
+		unknown: true,
+		check:   allFail(errorIs(rpc.ErrBlockNotFound, rpc.ErrTxNotFound)),
+	},
+	{
+		name:   "slow node",
+		faults: []mockgeth.Fault{{Delay: 200 * time.Millisecond}},
+		check:  allPass,
+	},
+	{
+		name:   "dropped connection",
+		faults: []mockgeth.Fault{{Drop: true}},
+		config: func(c *rpc.Config) { c.Retries = 0 },
+		check:  allFail(nil),
+	},
+	{
+		name:   "node restarting",
+		faults: []mockgeth.Fault{{Drop: true, Times: 2}},
+		check:  allPass,
+	},
+	{
+		name:   "node overloaded",
+		faults: []mockgeth.Fault{{Delay: 3 * time.Second, Times: 2}},
+		config: func(c *rpc.Config) { c.CallTimeout = time.Second },
+		check:  allPass,
+	},
+	{
+		name:   "json-rpc error is not retried",
+		faults: []mockgeth.Fault{{Error: &rpc.Error{Code: -32000, Message: "busy"}, Times: 1}},
+		check:  failOnly("VerifyRawHeader"),
+	},
+	{
+		name:   "call timeout",
+		faults: []mockgeth.Fault{{Delay: time.Second}},
+		config: func(c *rpc.Config) { c.CallTimeout = 100 * time.Millisecond; c.Retries = 1 },
+		check:  allFail(errorIs(context.DeadlineExceeded)),
+	},
+	{
+		name:    "overall timeout",
+		faults:  []mockgeth.Fault{{Delay: time.Second}},
+		timeout: 100 * time.Millisecond,
+		check:   allFail(errorIs(context.DeadlineExceeded)),
+	},
+	{name: "hive chain", hive: true, check: allPass},
+	{
+		name:   "uncle from node differs from raw uncle",
+		hive:   true,
+		faults: []mockgeth.Fault{{Method: "eth_getUncleByBlockNumberAndIndex", SetFields: map[string]json.RawMessage{"extraData": json.RawMessage(`"0x00"`)}}},
+		check:  failWithDiff("uncles hash", "extraData"),
+	},
+	{
+		name:   "uncle included before",
+		hive:   true,
+		faults: []mockgeth.Fault{{Method: "eth_getBlockByNumber", SetFields: map[string]json.RawMessage{"uncles": json.RawMessage(`["` + hiveUncle + `"]`)}}},
+		check:  failOnly("ommer rules"),
+	},
+	{
+		name:   "hive block bodies missing a transaction",
+		hive:   true,
+		faults: []mockgeth.Fault{{Method: "debug_getRawBlock", DropTransaction: true}},
+		check:  failOnly("VerifyRawBlock transactionsRoot", "VerifyReceiptConsistency"),
+	},
+}
+
+// loadExpected loads the fixtures a block is verified against
+func loadExpected(t *testing.T, dir string, number uint64) expected {
+	t.Helper()
+	exp := expected{number: number}
+	var err error
+	if exp.header, err = loader.HeaderFromJSON(loader.FixturePath(dir, int(number), "header")); err != nil {
+		t.Fatal(err)
+	}
+	if exp.receipts, err = loader.BlockReceiptsFromJSON(loader.FixturePath(dir, int(number), "receipts"), loader.NumberID(number)); err != nil {
+		t.Fatal(err)
+	}
+	txs, err := loader.BlockTransactionsFromJSON(loader.FixturePath(dir, int(number), "transactions"), loader.NumberID(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) > 0 {
+		exp.tx = txs[0]
+	}
+	exp.hash = exp.header.Hash()
+	exp.receiptsRoot = exp.header.ReceiptHash
+	return exp
+}
+
+// newServer serves the fixture block and the hive test chain, and returns
+// what they are verified against
+func newServer(t *testing.T) (*mockgeth.Server, expected, []expected) {
+	t.Helper()
+	fixture, err := mockgeth.LoadFixture(dataDir, blockNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hive, err := mockgeth.LoadChain(hiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := loadExpected(t, dataDir, blockNum)
+	exp.hash = common.HexToHash(headerHash)
+	exp.receiptsRoot = common.HexToHash(receiptsRoot)
+
+	hiveBlocks := make([]expected, len(hive))
+	for i, f := range hive {
+		hiveBlocks[i] = loadExpected(t, hiveDir, f.Number)
+		hiveBlocks[i].uncles = len(f.UnclesJSON) > 0
+	}
+	return mockgeth.NewServer(append([]*mockgeth.Fixture{fixture}, hive...)...), exp, hiveBlocks
+}
+
+// runScenario sets the faults of the scenario on the server and checks the
+// results of the Verify* functions over a new client for the endpoint
+func runScenario(server *mockgeth.Server, endpoint string, sc scenario, exp expected, hiveBlocks []expected) error {
+	server.Wait()
+	server.SetFaults(sc.faults...)
+	server.SetLegacyNames(sc.legacy)
+	defer server.SetFaults()
+
+	config := rpc.DefaultConfig()
+	config.Endpoint = endpoint
+	config.RetryBackoff = 10 * time.Millisecond
+	if sc.config != nil {
+		sc.config(&config)
+	}
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	ctx := context.Background()
+	if sc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sc.timeout)
+		defer cancel()
+	}
+	if sc.hive {
+		return sc.check(runHiveVerify(ctx, client, hiveBlocks))
+	}
+	if sc.unknown {
+		exp.number++
+		exp.tx = types.NewTx(&types.LegacyTx{Nonce: exp.number}) // not in any block
+	}
+	return sc.check(runVerify(ctx, client, exp))
+}
+
+// TestVerifyScenarios runs the Verify* functions against a mock node over
+// each transport, without faults and with each injected fault. Every
+// transport gets its own server, as the faults are set on the server.
+func TestVerifyScenarios(t *testing.T) {
+	transports := []struct {
+		name  string
+		start func(t *testing.T, server *mockgeth.Server) string
+	}{
+		{"http", func(t *testing.T, server *mockgeth.Server) string {
+			httpServer := server.Start()
+			t.Cleanup(httpServer.Close)
+			return httpServer.URL
+		}},
+		{"ipc", func(t *testing.T, server *mockgeth.Server) string {
+			path := filepath.Join(t.TempDir(), "geth.ipc")
+			l, err := server.StartIPC(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { l.Close() })
+			return path
+		}},
+		{"ws", func(t *testing.T, server *mockgeth.Server) string {
+			httpServer := server.Start()
+			t.Cleanup(httpServer.Close)
+			return mockgeth.WebSocketURL(httpServer)
+		}},
+	}
+	for _, tr := range transports {
+		tr := tr
+		t.Run(tr.name, func(t *testing.T) {
+			t.Parallel()
+			server, exp, hiveBlocks := newServer(t)
+			endpoint := tr.start(t, server)
+			for _, sc := range scenarios {
+				sc := sc
+				t.Run(sc.name, func(t *testing.T) {
+					if err := runScenario(server, endpoint, sc, exp, hiveBlocks); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestRawFixturesFromDisk checks that the server answers with the raw files
+// saved from a node, so that the raw data is checked against the json and
+// not against an encoding of the json
+func TestRawFixturesFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	files, err := filepath.Glob(filepath.Join(hiveDir, "block-1-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range append(files, loader.FixturePath(hiveDir, 1, "")) {
+		copyFile(t, file, filepath.Join(dir, filepath.Base(file)))
+	}
+	// block 1 with the raw header of block 2
+	copyFile(t, loader.FixturePath(hiveDir, 2, "header-rlp"), loader.FixturePath(dir, 1, "header-rlp"))
+
+	fixture, err := mockgeth.LoadFixture(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := mockgeth.NewServer(fixture).Start()
+	defer httpServer.Close()
+	config := rpc.DefaultConfig()
+	config.Endpoint = httpServer.URL
+	client := rpc.NewClient(config)
+	defer client.Close()
+
+	exp := loadExpected(t, dir, 1)
+	_, err = verify.VerifyRawHeader(context.Background(), client, exp.number, exp.header, exp.hash)
+	var mismatch *verify.MismatchError
+	if !errors.As(err, &mismatch) || len(mismatch.Diffs) == 0 {
+		t.Fatalf("VerifyRawHeader: expected a field-level mismatch, got %v", err)
+	}
+	_, err = verify.VerifyRawReceipts(context.Background(), client, exp.number, exp.receipts, exp.receiptsRoot)
+	if err != nil {
+		t.Fatalf("VerifyRawReceipts: %v", err)
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	data, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}